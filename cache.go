@@ -0,0 +1,189 @@
+package xtypes
+
+import (
+	"container/list"
+	"sync"
+)
+
+// Cache is the common interface of maps and caches, modelled on SafeMapOf.
+//
+// SafeMapOf, LRUCacheOf and CacheOf implement it.
+type Cache[K comparable, V any] interface {
+	// Get returns the value of the key.
+	Get(key K) (V, bool)
+
+	// Set sets the value of the key.
+	Set(key K, value V) error
+
+	// Del deletes the key.
+	Del(key K)
+
+	// Len returns the number of entries.
+	Len() int
+
+	// Keys returns all the keys.
+	Keys() []K
+}
+
+// CacheOf is a size-bounded cache with a selectable eviction policy.
+//
+// It is created with one of NewLFUCacheOf, NewARCCacheOf or NewTinyLFUCacheOf,
+// the capacity is a number of entries. Keys returns keys in an unspecified order.
+// CacheOf MUST be created using constructor.
+type CacheOf[K comparable, V any] struct {
+	mu      sync.Mutex // Protects fields below.
+	storage map[K]V
+	policy  evictionPolicy[K]
+}
+
+// evictionPolicy decides which keys a CacheOf keeps.
+//
+// It is NOT thread safe, CacheOf calls it with the mutex held.
+type evictionPolicy[K comparable] interface {
+	// hit records an access to a cached key.
+	hit(key K)
+
+	// add records a new key and returns the keys to evict, the new key is always kept.
+	add(key K) []K
+
+	// remove forgets a key deleted from the cache.
+	remove(key K)
+}
+
+// newCacheOf returns a new CacheOf with the policy.
+func newCacheOf[K comparable, V any](policy evictionPolicy[K]) *CacheOf[K, V] {
+	return &CacheOf[K, V]{
+		storage: make(map[K]V),
+		policy:  policy,
+	}
+}
+
+// Get returns object.
+func (c *CacheOf[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	r, ok := c.storage[key]
+	if ok {
+		c.policy.hit(key)
+	}
+
+	return r, ok
+}
+
+// Set sets the object, evicting other entries according to the policy if needed.
+func (c *CacheOf[K, V]) Set(key K, value V) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.storage[key]; ok {
+		c.storage[key] = value
+		c.policy.hit(key)
+
+		return nil
+	}
+
+	c.storage[key] = value
+
+	for _, k := range c.policy.add(key) {
+		delete(c.storage, k)
+	}
+
+	return nil
+}
+
+// Del deletes the object.
+func (c *CacheOf[K, V]) Del(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.storage[key]; ok {
+		delete(c.storage, key)
+		c.policy.remove(key)
+	}
+}
+
+// Len returns count of elements in the cache.
+func (c *CacheOf[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.storage)
+}
+
+// Keys returns all the keys as a slice.
+func (c *CacheOf[K, V]) Keys() []K {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]K, 0, len(c.storage))
+
+	for k := range c.storage {
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// keyList is a list of keys ordered by recency, the most recent first, with O(1) lookup.
+type keyList[K comparable] struct {
+	list  list.List
+	index map[K]*list.Element
+}
+
+// newKeyList returns an empty keyList.
+func newKeyList[K comparable]() *keyList[K] {
+	return &keyList[K]{
+		index: make(map[K]*list.Element),
+	}
+}
+
+// has returns true if the key is in the list.
+func (l *keyList[K]) has(key K) bool {
+	_, ok := l.index[key]
+
+	return ok
+}
+
+// pushFront adds the key to the front. The key MUST NOT be in the list.
+func (l *keyList[K]) pushFront(key K) {
+	l.index[key] = l.list.PushFront(key)
+}
+
+// moveToFront moves the key to the front if it is in the list.
+func (l *keyList[K]) moveToFront(key K) {
+	if e, ok := l.index[key]; ok {
+		l.list.MoveToFront(e)
+	}
+}
+
+// remove removes the key and returns true if it was in the list.
+func (l *keyList[K]) remove(key K) bool {
+	e, ok := l.index[key]
+	if !ok {
+		return false
+	}
+
+	l.list.Remove(e)
+	delete(l.index, key)
+
+	return true
+}
+
+// back returns the last key. The list MUST NOT be empty.
+func (l *keyList[K]) back() K {
+	return l.list.Back().Value.(K)
+}
+
+// popBack removes and returns the last key. The list MUST NOT be empty.
+func (l *keyList[K]) popBack() K {
+	key := l.back()
+	l.remove(key)
+
+	return key
+}
+
+// len returns the number of keys.
+func (l *keyList[K]) len() int {
+	return len(l.index)
+}
@@ -0,0 +1,97 @@
+package xtypes
+
+// arcPolicy is the Adaptive Replacement Cache policy by Megiddo and Modha.
+//
+// Keys seen once live in t1 and keys seen again in t2, b1 and b2 remember keys recently evicted from them.
+// A hit in a ghost list moves the target size p of t1 towards the list that would have kept the key,
+// so the cache adapts between recency and frequency and a scan only flushes t1.
+type arcPolicy[K comparable] struct {
+	c      int
+	p      int
+	t1, t2 *keyList[K]
+	b1, b2 *keyList[K]
+}
+
+// NewARCCacheOf returns a new CacheOf with the Adaptive Replacement Cache policy.
+func NewARCCacheOf[K comparable, V any](capacity int) *CacheOf[K, V] {
+	return newCacheOf[K, V](&arcPolicy[K]{
+		c:  max(capacity, 1),
+		t1: newKeyList[K](),
+		t2: newKeyList[K](),
+		b1: newKeyList[K](),
+		b2: newKeyList[K](),
+	})
+}
+
+// hit implements evictionPolicy.
+func (p *arcPolicy[K]) hit(key K) {
+	if p.t1.remove(key) {
+		p.t2.pushFront(key)
+		return
+	}
+
+	p.t2.moveToFront(key)
+}
+
+// add implements evictionPolicy.
+func (p *arcPolicy[K]) add(key K) []K {
+	var evicted []K
+
+	switch {
+	case p.b1.has(key):
+		p.p = min(p.c, p.p+max(p.b2.len()/p.b1.len(), 1))
+		p.b1.remove(key)
+
+		evicted = p.replace(false)
+		p.t2.pushFront(key)
+	case p.b2.has(key):
+		p.p = max(0, p.p-max(p.b1.len()/p.b2.len(), 1))
+		p.b2.remove(key)
+
+		evicted = p.replace(true)
+		p.t2.pushFront(key)
+	default:
+		if p.t1.len()+p.b1.len() >= p.c {
+			if p.t1.len() < p.c {
+				p.b1.popBack()
+				evicted = p.replace(false)
+			} else {
+				evicted = append(evicted, p.t1.popBack())
+			}
+		} else if total := p.t1.len() + p.t2.len() + p.b1.len() + p.b2.len(); total >= p.c {
+			if total >= 2*p.c {
+				p.b2.popBack()
+			}
+
+			evicted = p.replace(false)
+		}
+
+		p.t1.pushFront(key)
+	}
+
+	return evicted
+}
+
+// remove implements evictionPolicy.
+func (p *arcPolicy[K]) remove(key K) {
+	_ = p.t1.remove(key) || p.t2.remove(key) || p.b1.remove(key) || p.b2.remove(key)
+}
+
+// replace evicts a key from t1 or t2 into its ghost list if the cache is full.
+func (p *arcPolicy[K]) replace(inB2 bool) []K {
+	if p.t1.len()+p.t2.len() < p.c {
+		return nil
+	}
+
+	if p.t1.len() > 0 && (p.t1.len() > p.p || (inB2 && p.t1.len() == p.p) || p.t2.len() == 0) {
+		key := p.t1.popBack()
+		p.b1.pushFront(key)
+
+		return []K{key}
+	}
+
+	key := p.t2.popBack()
+	p.b2.pushFront(key)
+
+	return []K{key}
+}
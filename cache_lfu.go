@@ -0,0 +1,113 @@
+package xtypes
+
+import (
+	"container/list"
+)
+
+// lfuPolicy evicts the least frequently used key, the least recently used one among equals.
+//
+// Keys are kept in lists by access count, so all operations are O(1).
+type lfuPolicy[K comparable] struct {
+	capacity int
+	items    map[K]*lfuItem[K]
+	freqs    map[int]*list.List // Of *lfuItem, the most recently used first.
+	min      int                // Lowest access count, stale after remove until the next eviction.
+}
+
+// lfuItem is a key with its access count.
+type lfuItem[K comparable] struct {
+	key  K
+	freq int
+	elem *list.Element
+}
+
+// NewLFUCacheOf returns a new CacheOf which evicts the least frequently used entries.
+func NewLFUCacheOf[K comparable, V any](capacity int) *CacheOf[K, V] {
+	return newCacheOf[K, V](&lfuPolicy[K]{
+		capacity: max(capacity, 1),
+		items:    make(map[K]*lfuItem[K]),
+		freqs:    make(map[int]*list.List),
+	})
+}
+
+// hit implements evictionPolicy.
+func (p *lfuPolicy[K]) hit(key K) {
+	it := p.items[key]
+
+	if p.unlink(it) && it.freq == p.min {
+		p.min++
+	}
+
+	it.freq++
+	p.link(it)
+}
+
+// add implements evictionPolicy.
+func (p *lfuPolicy[K]) add(key K) []K {
+	var evicted []K
+
+	if len(p.items) >= p.capacity {
+		evicted = append(evicted, p.evict())
+	}
+
+	it := &lfuItem[K]{key: key, freq: 1}
+
+	p.items[key] = it
+	p.link(it)
+	p.min = 1
+
+	return evicted
+}
+
+// remove implements evictionPolicy.
+func (p *lfuPolicy[K]) remove(key K) {
+	if it, ok := p.items[key]; ok {
+		p.unlink(it)
+		delete(p.items, key)
+	}
+}
+
+// evict removes and returns the least frequently used key.
+func (p *lfuPolicy[K]) evict() K {
+	if _, ok := p.freqs[p.min]; !ok {
+		p.min = 0
+
+		for f := range p.freqs {
+			if p.min == 0 || f < p.min {
+				p.min = f
+			}
+		}
+	}
+
+	it := p.freqs[p.min].Back().Value.(*lfuItem[K])
+
+	p.unlink(it)
+	delete(p.items, it.key)
+
+	return it.key
+}
+
+// link adds the item to the list of its access count.
+func (p *lfuPolicy[K]) link(it *lfuItem[K]) {
+	l, ok := p.freqs[it.freq]
+	if !ok {
+		l = list.New()
+		p.freqs[it.freq] = l
+	}
+
+	it.elem = l.PushFront(it)
+}
+
+// unlink removes the item from the list of its access count and returns true if the list is left empty.
+func (p *lfuPolicy[K]) unlink(it *lfuItem[K]) bool {
+	l := p.freqs[it.freq]
+	l.Remove(it.elem)
+
+	if l.Len() > 0 {
+		return false
+	}
+
+	delete(p.freqs, it.freq)
+
+	return true
+}
@@ -0,0 +1,288 @@
+package xtypes
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// Every map and cache can be used where a Cache is expected.
+var (
+	_ Cache[string, interface{}] = (*SafeMap)(nil)
+	_ Cache[string, int]         = (*SafeMapOf[string, int])(nil)
+	_ Cache[string, int]         = (*LRUCacheOf[string, int])(nil)
+	_ Cache[string, int]         = (*CacheOf[string, int])(nil)
+)
+
+// cacheCapacity is the capacity of caches under trace replay.
+const cacheCapacity = 500
+
+// cachePolicies creates caches of every policy for trace replay.
+var cachePolicies = []struct {
+	name string
+	fn   func() Cache[uint64, uint64]
+}{
+	{"LRU", func() Cache[uint64, uint64] { return NewLRUCacheOf[uint64, uint64](cacheCapacity, nil, nil) }},
+	{"LFU", func() Cache[uint64, uint64] { return NewLFUCacheOf[uint64, uint64](cacheCapacity) }},
+	{"ARC", func() Cache[uint64, uint64] { return NewARCCacheOf[uint64, uint64](cacheCapacity) }},
+	{"W-TinyLFU", func() Cache[uint64, uint64] { return NewTinyLFUCacheOf[uint64, uint64](cacheCapacity, hashUint64) }},
+}
+
+func TestCacheOf(t *testing.T) {
+	for _, p := range cachePolicies[1:] {
+		t.Run(p.name, func(t *testing.T) {
+			c := p.fn()
+
+			c.Set(1, 10)
+			c.Set(1, 11)
+
+			if v, ok := c.Get(1); !ok || v != 11 {
+				t.Fatalf("expected %d, got %d", 11, v)
+			}
+
+			for i := uint64(0); i < 2*cacheCapacity; i++ {
+				c.Set(i, i)
+
+				// The new key is always kept.
+				if _, ok := c.Get(i); !ok {
+					t.Fatalf("expected key %d to be cached", i)
+				}
+
+				if l := c.Len(); l > cacheCapacity {
+					t.Fatalf("expected at most %d, got %d", cacheCapacity, l)
+				}
+			}
+
+			keys := c.Keys()
+			if len(keys) != cacheCapacity {
+				t.Fatalf("expected %d, got %d", cacheCapacity, len(keys))
+			}
+
+			for _, k := range keys {
+				c.Del(k)
+			}
+
+			if l := c.Len(); l != 0 {
+				t.Fatalf("expected %d, got %d", 0, l)
+			}
+
+			// The policy is consistent after deletions.
+			for i := uint64(0); i < 2*cacheCapacity; i++ {
+				c.Set(i, i)
+			}
+
+			if l := c.Len(); l != cacheCapacity {
+				t.Fatalf("expected %d, got %d", cacheCapacity, l)
+			}
+		})
+	}
+}
+
+func TestCacheOf_HitRatio(t *testing.T) {
+	traces := []struct {
+		name  string
+		trace []uint64
+	}{
+		{"zipf", zipfTrace(1, 100000)},
+		{"scan", scanTrace(2, 100000)},
+	}
+
+	ratios := make(map[string]map[string]float64)
+
+	for _, tr := range traces {
+		ratios[tr.name] = make(map[string]float64)
+
+		for _, p := range cachePolicies {
+			ratio := replayTrace(p.fn(), tr.trace)
+			ratios[tr.name][p.name] = ratio
+
+			t.Logf("%-5s %-10s hit ratio %.2f%%", tr.name, p.name, ratio*100)
+		}
+	}
+
+	// Frequency-aware policies beat LRU on a skewed workload.
+	for _, name := range []string{"LFU", "ARC", "W-TinyLFU"} {
+		if ratios["zipf"][name] < ratios["zipf"]["LRU"] {
+			t.Errorf("expected %s to beat LRU on zipf, got %.4f < %.4f", name, ratios["zipf"][name], ratios["zipf"]["LRU"])
+		}
+	}
+
+	// Scan-resistant policies keep the hot set while scans go through.
+	for _, name := range []string{"ARC", "W-TinyLFU"} {
+		if ratios["scan"][name] < ratios["scan"]["LRU"]*1.1 {
+			t.Errorf("expected %s to beat LRU on scan, got %.4f < %.4f", name, ratios["scan"][name], ratios["scan"]["LRU"])
+		}
+	}
+}
+
+func TestLFUPolicy_Order(t *testing.T) {
+	c := NewLFUCacheOf[string, int](3)
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+
+	c.Get("a")
+	c.Get("a")
+	c.Get("c")
+
+	// "b" is the least frequently used.
+	c.Set("d", 4)
+
+	expected := []string{"a", "c", "d"}
+	if actual := sortedKeys(c); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+
+	// "c" and "d" are used once more, "d" most recently, so "c" goes.
+	c.Get("d")
+	c.Set("e", 5)
+
+	expected = []string{"a", "d", "e"}
+	if actual := sortedKeys(c); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestARCPolicy_GhostHit(t *testing.T) {
+	c := NewARCCacheOf[string, int](2)
+	p := c.policy.(*arcPolicy[string])
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Get("a")
+	c.Set("c", 3)
+
+	if c.Len() != 2 || !p.b1.has("b") {
+		t.Fatal("expected b to be evicted to the ghost list")
+	}
+
+	// A ghost hit makes the key frequent and grows the recency target.
+	c.Set("b", 2)
+
+	if !p.t2.has("b") || !p.b2.has("a") {
+		t.Fatal("expected b to be frequent and a to be evicted")
+	}
+
+	if p.p != 1 {
+		t.Fatalf("expected %d, got %d", 1, p.p)
+	}
+}
+
+func TestTinyLFUPolicy_Admission(t *testing.T) {
+	c := NewTinyLFUCacheOf[uint64, uint64](100, hashUint64)
+
+	// A popular set fills the main segments.
+	for r := 0; r < 5; r++ {
+		for i := uint64(0); i < 99; i++ {
+			if _, ok := c.Get(i); !ok {
+				c.Set(i, i)
+			}
+		}
+	}
+
+	// Keys seen once are rejected when they leave the window.
+	for i := uint64(1000); i < 1300; i++ {
+		c.Set(i, i)
+	}
+
+	for i := uint64(0); i < 99; i++ {
+		if _, ok := c.Get(i); !ok {
+			t.Fatalf("expected key %d to stay cached", i)
+		}
+	}
+}
+
+func TestCountMinSketch(t *testing.T) {
+	s := newCountMinSketch(64)
+
+	for i := 0; i < 10; i++ {
+		s.add(1)
+	}
+
+	s.add(2)
+
+	if e := s.estimate(1); e != 10 {
+		t.Fatalf("expected %d, got %d", 10, e)
+	}
+
+	if e := s.estimate(2); e != 1 {
+		t.Fatalf("expected %d, got %d", 1, e)
+	}
+
+	// Counters saturate.
+	for i := 0; i < 20; i++ {
+		s.add(1)
+	}
+
+	if e := s.estimate(1); e != sketchMax {
+		t.Fatalf("expected %d, got %d", sketchMax, e)
+	}
+
+	// And age.
+	for i := 0; i < s.resetAt; i++ {
+		s.add(uint64(100 + i))
+	}
+
+	if e := s.estimate(1); e >= sketchMax {
+		t.Fatalf("expected less than %d, got %d", sketchMax, e)
+	}
+}
+
+// replayTrace runs the trace through the cache, filling it on misses, and returns the hit ratio.
+func replayTrace(c Cache[uint64, uint64], trace []uint64) float64 {
+	hits := 0
+
+	for _, k := range trace {
+		if _, ok := c.Get(k); ok {
+			hits++
+			continue
+		}
+
+		c.Set(k, k)
+	}
+
+	return float64(hits) / float64(len(trace))
+}
+
+// zipfTrace returns n keys with a Zipf distribution over ten times the cache capacity.
+func zipfTrace(seed int64, n int) []uint64 {
+	z := rand.NewZipf(rand.New(rand.NewSource(seed)), 1.1, 1, 10*cacheCapacity)
+
+	trace := make([]uint64, n)
+	for i := range trace {
+		trace[i] = z.Uint64()
+	}
+
+	return trace
+}
+
+// scanTrace returns n keys of a Zipf workload interrupted by scans of keys which are never seen again.
+func scanTrace(seed int64, n int) []uint64 {
+	trace := zipfTrace(seed, n)
+
+	next := uint64(1 << 32)
+
+	for i := 0; i < len(trace); i += 4 * cacheCapacity {
+		for j := i; j < i+2*cacheCapacity && j < len(trace); j++ {
+			trace[j] = next
+			next++
+		}
+	}
+
+	return trace
+}
+
+// hashUint64 hashes keys of caches under test.
+func hashUint64(k uint64) uint64 {
+	return k
+}
+
+// sortedKeys returns keys of the cache in increasing order.
+func sortedKeys(c Cache[string, int]) []string {
+	keys := c.Keys()
+	sort.Strings(keys)
+
+	return keys
+}
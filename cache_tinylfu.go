@@ -0,0 +1,190 @@
+package xtypes
+
+// tinyLFUPolicy is the W-TinyLFU policy of Caffeine.
+//
+// New keys enter a small LRU window. Keys leaving the window are admitted to the main segmented LRU
+// only if a count-min sketch estimates them to be more frequent than its victim, so a scan of keys
+// seen once cannot flush the main segments. Keys hit in probation are promoted to protected.
+type tinyLFUPolicy[K comparable] struct {
+	hash         func(key K) uint64
+	sketch       *countMinSketch
+	window       *keyList[K]
+	probation    *keyList[K]
+	protected    *keyList[K]
+	windowCap    int
+	mainCap      int
+	protectedCap int
+}
+
+// NewTinyLFUCacheOf returns a new CacheOf with the W-TinyLFU policy.
+//
+// The hash function feeds the frequency sketch.
+func NewTinyLFUCacheOf[K comparable, V any](capacity int, hash func(key K) uint64) *CacheOf[K, V] {
+	capacity = max(capacity, 1)
+
+	windowCap := max(capacity/100, 1)
+	mainCap := capacity - windowCap
+
+	return newCacheOf[K, V](&tinyLFUPolicy[K]{
+		hash:         hash,
+		sketch:       newCountMinSketch(capacity),
+		window:       newKeyList[K](),
+		probation:    newKeyList[K](),
+		protected:    newKeyList[K](),
+		windowCap:    windowCap,
+		mainCap:      mainCap,
+		protectedCap: mainCap * 8 / 10,
+	})
+}
+
+// hit implements evictionPolicy.
+func (p *tinyLFUPolicy[K]) hit(key K) {
+	p.sketch.add(p.hash(key))
+
+	switch {
+	case p.window.has(key):
+		p.window.moveToFront(key)
+	case p.probation.remove(key):
+		p.protected.pushFront(key)
+
+		if p.protected.len() > p.protectedCap {
+			p.probation.pushFront(p.protected.popBack())
+		}
+	default:
+		p.protected.moveToFront(key)
+	}
+}
+
+// add implements evictionPolicy.
+func (p *tinyLFUPolicy[K]) add(key K) []K {
+	p.sketch.add(p.hash(key))
+	p.window.pushFront(key)
+
+	if p.window.len() <= p.windowCap {
+		return nil
+	}
+
+	candidate := p.window.popBack()
+
+	if p.probation.len()+p.protected.len() < p.mainCap {
+		p.probation.pushFront(candidate)
+		return nil
+	}
+
+	if p.mainCap == 0 {
+		return []K{candidate}
+	}
+
+	victims := p.probation
+	if victims.len() == 0 {
+		victims = p.protected
+	}
+
+	victim := victims.back()
+
+	if p.sketch.estimate(p.hash(candidate)) <= p.sketch.estimate(p.hash(victim)) {
+		return []K{candidate}
+	}
+
+	victims.remove(victim)
+	p.probation.pushFront(candidate)
+
+	return []K{victim}
+}
+
+// remove implements evictionPolicy.
+func (p *tinyLFUPolicy[K]) remove(key K) {
+	_ = p.window.remove(key) || p.probation.remove(key) || p.protected.remove(key)
+}
+
+// sketchDepth is the number of rows of countMinSketch.
+const sketchDepth = 4
+
+// sketchMax is the value counters of countMinSketch saturate at.
+const sketchMax = 15
+
+// sketchWidth is the number of counters per row of countMinSketch for each entry of the capacity.
+const sketchWidth = 8
+
+// countMinSketch estimates access frequencies of hashed keys in fixed memory.
+//
+// Rows are several times wider than the capacity to keep collisions rare.
+// Counters are halved once the number of additions reaches ten times the capacity,
+// so old popularity fades away.
+type countMinSketch struct {
+	rows      [sketchDepth][]uint8
+	mask      uint64
+	additions int
+	resetAt   int
+}
+
+// sketchSeeds decorrelate rows of countMinSketch.
+var sketchSeeds = [sketchDepth]uint64{
+	0xc3a5c85c97cb3127, 0xb492b66fbe98f273, 0x9ae16a3b2f90404f, 0xcbf29ce484222325,
+}
+
+// newCountMinSketch returns a sketch sized for the capacity.
+func newCountMinSketch(capacity int) *countMinSketch {
+	width := 16
+	for width < sketchWidth*capacity {
+		width <<= 1
+	}
+
+	s := &countMinSketch{
+		mask:    uint64(width - 1),
+		resetAt: 10 * capacity,
+	}
+
+	for i := range s.rows {
+		s.rows[i] = make([]uint8, width)
+	}
+
+	return s
+}
+
+// add increments the counters of the hash.
+func (s *countMinSketch) add(h uint64) {
+	for i := range s.rows {
+		if c := &s.rows[i][s.index(h, i)]; *c < sketchMax {
+			*c++
+		}
+	}
+
+	s.additions++
+	if s.additions >= s.resetAt {
+		s.reset()
+	}
+}
+
+// estimate returns the estimated frequency of the hash.
+func (s *countMinSketch) estimate(h uint64) uint8 {
+	result := uint8(sketchMax)
+
+	for i := range s.rows {
+		result = min(result, s.rows[i][s.index(h, i)])
+	}
+
+	return result
+}
+
+// reset halves all counters.
+func (s *countMinSketch) reset() {
+	for i := range s.rows {
+		for j := range s.rows[i] {
+			s.rows[i][j] >>= 1
+		}
+	}
+
+	s.additions /= 2
+}
+
+// index returns the position of the hash in the row.
+func (s *countMinSketch) index(h uint64, row int) uint64 {
+	// The splitmix64 finalizer spreads hashes which differ in a few bits only.
+	h = (h + sketchSeeds[row]) * 0xbf58476d1ce4e5b9
+	h ^= h >> 31
+	h *= 0x94d049bb133111eb
+	h ^= h >> 29
+
+	return h & s.mask
+}
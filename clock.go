@@ -0,0 +1,58 @@
+package xtypes
+
+import (
+	"time"
+)
+
+// Clock provides the current time and timers.
+//
+// It allows to drive time-dependent types deterministically in tests.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer creates a new Timer which sends the current time on its channel after at least d.
+	NewTimer(d time.Duration) Timer
+}
+
+// Timer is a single event timer created by Clock.
+type Timer interface {
+	// C returns the channel on which the time is delivered.
+	C() <-chan time.Time
+
+	// Stop prevents the Timer from firing. It returns false if the timer has already fired or been stopped.
+	Stop() bool
+}
+
+// SystemClock returns the Clock backed by the time package.
+func SystemClock() Clock {
+	return systemClock{}
+}
+
+// systemClock implements Clock with the time package.
+type systemClock struct{}
+
+// Now implements Clock.
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+// NewTimer implements Clock.
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+// systemTimer implements Timer with time.Timer.
+type systemTimer struct {
+	t *time.Timer
+}
+
+// C implements Timer.
+func (t systemTimer) C() <-chan time.Time {
+	return t.t.C
+}
+
+// Stop implements Timer.
+func (t systemTimer) Stop() bool {
+	return t.t.Stop()
+}
@@ -0,0 +1,164 @@
+package xtypes
+
+import (
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeClock is a Clock driven manually by tests.
+type fakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	timers  []*fakeTimer
+	created int // Number of timers created so far.
+}
+
+// fakeTimer is a Timer created by fakeClock.
+type fakeTimer struct {
+	clock *fakeClock
+	at    time.Time
+	ch    chan time.Time
+}
+
+func newFakeClock() *fakeClock {
+	c := &fakeClock{
+		now: time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	c.cond = sync.NewCond(&c.mu)
+
+	return c
+}
+
+// Now implements Clock.
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+// NewTimer implements Clock.
+func (c *fakeClock) NewTimer(d time.Duration) Timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &fakeTimer{clock: c, at: c.now.Add(d), ch: make(chan time.Time, 1)}
+
+	if d <= 0 {
+		t.ch <- c.now
+	} else {
+		c.timers = append(c.timers, t)
+	}
+
+	c.created++
+	c.cond.Broadcast()
+
+	return t
+}
+
+// Advance moves the clock forward and fires the timers which are due.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+
+	active := c.timers[:0]
+
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			active = append(active, t)
+			continue
+		}
+
+		t.ch <- c.now
+	}
+
+	c.timers = active
+}
+
+// WaitTimers blocks until at least n timers have been created since the clock was made.
+func (c *fakeClock) WaitTimers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for c.created < n {
+		c.cond.Wait()
+	}
+}
+
+// C implements Timer.
+func (t *fakeTimer) C() <-chan time.Time {
+	return t.ch
+}
+
+// Stop implements Timer.
+func (t *fakeTimer) Stop() bool {
+	c := t.clock
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for i, v := range c.timers {
+		if v == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestSystemClock(t *testing.T) {
+	c := SystemClock()
+
+	if c.Now().IsZero() {
+		t.Fatal("expected non-zero time")
+	}
+
+	timer := c.NewTimer(time.Hour)
+	if !timer.Stop() {
+		t.Fatal("expected active timer to be stopped")
+	}
+
+	timer = c.NewTimer(0)
+	<-timer.C()
+
+	if timer.Stop() {
+		t.Fatal("expected fired timer not to be stopped")
+	}
+}
+
+func TestFakeClock(t *testing.T) {
+	c := newFakeClock()
+	start := c.Now()
+
+	t1 := c.NewTimer(time.Second)
+	t2 := c.NewTimer(2 * time.Second)
+
+	c.Advance(time.Second)
+
+	select {
+	case v := <-t1.C():
+		if !v.Equal(start.Add(time.Second)) {
+			t.Fatalf("expected %v, got %v", start.Add(time.Second), v)
+		}
+	default:
+		t.Fatal("expected timer to fire")
+	}
+
+	if !t2.Stop() {
+		t.Fatal("expected active timer to be stopped")
+	}
+
+	c.Advance(time.Second)
+
+	select {
+	case <-t2.C():
+		t.Fatal("expected stopped timer not to fire")
+	default:
+	}
+}
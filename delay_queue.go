@@ -0,0 +1,280 @@
+package xtypes
+
+import (
+	"container/heap"
+	"context"
+	"sync"
+	"time"
+)
+
+// DelayQueue is a queue of items which become available at a scheduled time.
+//
+// Items are ordered by their ready time, items with the same ready time come out in the order they have been added.
+// An item can be popped only once its time has passed according to the clock of the queue.
+// DelayQueue MUST be created using constructor.
+type DelayQueue[T any] struct {
+	mu        sync.Mutex // Protects items.
+	items     pqHeap[delayEntry[T]]
+	consumers waiters // Consumers blocked in PopWait.
+	clock     Clock
+	closed    bool
+}
+
+// delayEntry is an item scheduled at a time.
+type delayEntry[T any] struct {
+	item T
+	at   time.Time
+}
+
+// NewDelayQueue creates and inits a new DelayQueue.
+//
+// If clock is nil, the system clock is used.
+func NewDelayQueue[T any](hint int, clock Clock) *DelayQueue[T] {
+	if clock == nil {
+		clock = SystemClock()
+	}
+
+	dq := &DelayQueue[T]{
+		items: newPQHeap(hint, lessDelayEntry[T]),
+		clock: clock,
+	}
+
+	dq.items.stable = true
+
+	heap.Init(&dq.items)
+
+	return dq
+}
+
+// Push adds an item which becomes available at the given time.
+func (dq *DelayQueue[T]) Push(item T, at time.Time) error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	if dq.items.items == nil {
+		return ErrInvalidQueue
+	}
+
+	if dq.closed {
+		return ErrClosed
+	}
+
+	heap.Push(&dq.items, delayEntry[T]{item: item, at: at})
+
+	// Consumers wait for the first item only, so they need to know when it changes.
+	if dq.items.items[0].seq == dq.items.seq-1 {
+		dq.consumers.signal(1)
+	}
+
+	return nil
+}
+
+// PushAfter adds an item which becomes available after d from now.
+func (dq *DelayQueue[T]) PushAfter(item T, d time.Duration) error {
+	return dq.Push(item, dq.clock.Now().Add(d))
+}
+
+// Pop returns the first ready item from the queue.
+// If there are no items - ErrEmptyQueue or ErrClosed for a closed queue is returned,
+// if no item is ready yet - ErrNotReady is returned.
+func (dq *DelayQueue[T]) Pop() (T, error) {
+	var zero T
+
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	if dq.items.Len() == 0 {
+		if dq.closed {
+			return zero, ErrClosed
+		}
+
+		return zero, ErrEmptyQueue
+	}
+
+	if dq.wait() > 0 {
+		return zero, ErrNotReady
+	}
+
+	return dq.pop(), nil
+}
+
+// Get returns up to requested n of ready items.
+func (dq *DelayQueue[T]) Get(n int) ([]T, error) {
+	if n < 1 {
+		return []T{}, nil
+	}
+
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	if dq.items.items == nil {
+		return nil, ErrInvalidQueue
+	}
+
+	if dq.closed && dq.items.Len() == 0 {
+		return nil, ErrClosed
+	}
+
+	result := make([]T, 0, min(n, dq.items.Len()))
+
+	for i := 0; i < n && dq.items.Len() > 0 && dq.wait() <= 0; i++ {
+		result = append(result, dq.pop())
+	}
+
+	return result, nil
+}
+
+// PopWait returns the first item from the queue once it is ready.
+// It blocks until an item is ready, the queue is closed or ctx is done.
+// ErrClosed is returned once the queue is closed and has no items left, ctx.Err() is returned on cancellation.
+//
+// Items left in a closed queue are still returned when they are ready.
+func (dq *DelayQueue[T]) PopWait(ctx context.Context) (T, error) {
+	var zero T
+
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	if dq.items.items == nil {
+		return zero, ErrInvalidQueue
+	}
+
+	for {
+		if dq.items.Len() == 0 && dq.closed {
+			return zero, ErrClosed
+		}
+
+		var timer Timer
+		var fired <-chan time.Time
+
+		if dq.items.Len() > 0 {
+			d := dq.wait()
+			if d <= 0 {
+				return dq.pop(), nil
+			}
+
+			timer = dq.clock.NewTimer(d)
+			fired = timer.C()
+		}
+
+		wt := dq.consumers.enqueue()
+
+		dq.mu.Unlock()
+
+		select {
+		case <-wt.ready:
+		case <-fired:
+		case <-ctx.Done():
+		}
+
+		dq.mu.Lock()
+
+		if timer != nil {
+			timer.Stop()
+		}
+
+		if err := ctx.Err(); err != nil {
+			dq.consumers.leave(wt)
+
+			// It may have been the only consumer with a timer for the first item.
+			if dq.items.Len() > 0 {
+				dq.consumers.signal(1)
+			}
+
+			return zero, err
+		}
+
+		dq.consumers.remove(wt)
+	}
+}
+
+// Peek returns the first item and its ready time without modifying the queue.
+// If the queue is empty - the zero values are returned.
+func (dq *DelayQueue[T]) Peek() (T, time.Time) {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	if dq.items.Len() == 0 {
+		var zero T
+
+		return zero, time.Time{}
+	}
+
+	e := dq.items.items[0].item
+
+	return e.item, e.at
+}
+
+// Len returns the len of the queue, including items which are not ready yet.
+func (dq *DelayQueue[T]) Len() int {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	return dq.items.Len()
+}
+
+// Empty returns true if the queue is empty.
+func (dq *DelayQueue[T]) Empty() bool {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	return dq.items.Len() == 0
+}
+
+// Close closes the queue.
+//
+// Push returns ErrClosed afterwards, blocked consumers are woken up.
+// Closing a closed queue returns ErrClosed.
+func (dq *DelayQueue[T]) Close() error {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	if dq.items.items == nil {
+		return ErrInvalidQueue
+	}
+
+	if dq.closed {
+		return ErrClosed
+	}
+
+	dq.closed = true
+	dq.consumers.broadcast()
+
+	return nil
+}
+
+// Drain removes and returns all items left in the queue ordered by ready time, whether they are ready or not.
+func (dq *DelayQueue[T]) Drain() []T {
+	dq.mu.Lock()
+	defer dq.mu.Unlock()
+
+	result := make([]T, 0, dq.items.Len())
+
+	for dq.items.Len() > 0 {
+		result = append(result, dq.pop())
+	}
+
+	return result
+}
+
+// wait returns the time left until the first item is ready.
+// The queue MUST NOT be empty.
+func (dq *DelayQueue[T]) wait() time.Duration {
+	return dq.items.items[0].item.at.Sub(dq.clock.Now())
+}
+
+// pop removes the first item and lets a blocked consumer wait for the next one.
+func (dq *DelayQueue[T]) pop() T {
+	item := heap.Pop(&dq.items).(delayEntry[T]).item
+
+	if dq.items.Len() > 0 {
+		dq.consumers.signal(1)
+	}
+
+	return item
+}
+
+// lessDelayEntry orders entries by ready time.
+func lessDelayEntry[T any](a, b delayEntry[T]) bool {
+	return a.at.Before(b.at)
+}
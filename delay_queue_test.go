@@ -0,0 +1,308 @@
+package xtypes
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewDelayQueue(t *testing.T) {
+	dq := NewDelayQueue[int](10, nil)
+	if dq == nil {
+		t.Fatal("failed to create queue")
+	}
+}
+
+func TestDelayQueue_Pop(t *testing.T) {
+	clock := newFakeClock()
+	dq := NewDelayQueue[string](1, clock)
+
+	if _, err := dq.Pop(); err != ErrEmptyQueue {
+		t.Fatalf("expected %v, got %v", ErrEmptyQueue, err)
+	}
+
+	dq.PushAfter("a", time.Second)
+
+	if _, err := dq.Pop(); err != ErrNotReady {
+		t.Fatalf("expected %v, got %v", ErrNotReady, err)
+	}
+
+	clock.Advance(time.Second)
+
+	v, err := dq.Pop()
+	if err != nil {
+		t.Fatalf("failed to pop queue: %v", err)
+	}
+
+	if v != "a" {
+		t.Fatalf("expected %s, got %s", "a", v)
+	}
+}
+
+func TestDelayQueue_Order(t *testing.T) {
+	clock := newFakeClock()
+	dq := NewDelayQueue[string](4, clock)
+
+	now := clock.Now()
+
+	dq.Push("c", now.Add(3*time.Second))
+	dq.Push("a1", now.Add(time.Second))
+	dq.Push("b", now.Add(2*time.Second))
+	dq.Push("a2", now.Add(time.Second))
+
+	v, at := dq.Peek()
+	if v != "a1" || !at.Equal(now.Add(time.Second)) {
+		t.Fatalf("expected %s at %v, got %s at %v", "a1", now.Add(time.Second), v, at)
+	}
+
+	clock.Advance(2 * time.Second)
+
+	ready, err := dq.Get(10)
+	if err != nil {
+		t.Fatalf("failed to get from queue: %v", err)
+	}
+
+	if expected := []string{"a1", "a2", "b"}; !reflect.DeepEqual(expected, ready) {
+		t.Fatalf("expected %v, got %v", expected, ready)
+	}
+
+	if l := dq.Len(); l != 1 {
+		t.Fatalf("expected %d, got %d", 1, l)
+	}
+}
+
+func TestDelayQueue_PopWait(t *testing.T) {
+	clock := newFakeClock()
+	dq := NewDelayQueue[int](1, clock)
+
+	dq.PushAfter(1, time.Minute)
+
+	result := make(chan int)
+
+	go func() {
+		v, err := dq.PopWait(context.Background())
+		if err != nil {
+			t.Errorf("failed to pop queue: %v", err)
+		}
+
+		result <- v
+	}()
+
+	clock.WaitTimers(1)
+	clock.Advance(time.Minute)
+
+	if v := <-result; v != 1 {
+		t.Fatalf("expected %d, got %d", 1, v)
+	}
+}
+
+func TestDelayQueue_PopWaitEarlierItem(t *testing.T) {
+	clock := newFakeClock()
+	dq := NewDelayQueue[int](1, clock)
+
+	dq.PushAfter(2, time.Hour)
+
+	result := make(chan int)
+
+	go func() {
+		v, err := dq.PopWait(context.Background())
+		if err != nil {
+			t.Errorf("failed to pop queue: %v", err)
+		}
+
+		result <- v
+	}()
+
+	clock.WaitTimers(1)
+
+	// The consumer waits for the hour, an earlier item must make it wait less.
+	dq.PushAfter(1, time.Second)
+
+	clock.WaitTimers(2)
+	clock.Advance(time.Second)
+
+	if v := <-result; v != 1 {
+		t.Fatalf("expected %d, got %d", 1, v)
+	}
+}
+
+func TestDelayQueue_PopWaitEmpty(t *testing.T) {
+	clock := newFakeClock()
+	dq := NewDelayQueue[int](1, clock)
+
+	const consumers = 2
+
+	result := make(chan int, consumers)
+
+	for i := 0; i < consumers; i++ {
+		go func() {
+			v, err := dq.PopWait(context.Background())
+			if err != nil {
+				t.Errorf("failed to pop queue: %v", err)
+			}
+
+			result <- v
+		}()
+	}
+
+	dq.PushAfter(1, time.Second)
+	dq.PushAfter(2, 2*time.Second)
+
+	// Each consumer waits for its own item.
+	clock.WaitTimers(1)
+	clock.Advance(time.Second)
+
+	if v := <-result; v != 1 {
+		t.Fatalf("expected %d, got %d", 1, v)
+	}
+
+	clock.WaitTimers(2)
+	clock.Advance(time.Second)
+
+	if v := <-result; v != 2 {
+		t.Fatalf("expected %d, got %d", 2, v)
+	}
+}
+
+func TestDelayQueue_PopWaitCancel(t *testing.T) {
+	clock := newFakeClock()
+	dq := NewDelayQueue[int](1, clock)
+
+	dq.PushAfter(1, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+
+	go func() {
+		_, err := dq.PopWait(ctx)
+		done <- err
+	}()
+
+	clock.WaitTimers(1)
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	if n := dq.consumers.len(); n != 0 {
+		t.Fatalf("expected %d waiters, got %d", 0, n)
+	}
+}
+
+func TestDelayQueue_PopWaitCancelTimer(t *testing.T) {
+	clock := newFakeClock()
+	dq := NewDelayQueue[int](1, clock)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	cancelled := make(chan error)
+	result := make(chan int)
+
+	go func() {
+		_, err := dq.PopWait(ctx)
+		cancelled <- err
+	}()
+
+	waitConsumers(dq, 1)
+
+	go func() {
+		v, err := dq.PopWait(context.Background())
+		if err != nil {
+			t.Errorf("failed to pop queue: %v", err)
+		}
+
+		result <- v
+	}()
+
+	waitConsumers(dq, 2)
+
+	// Only the first consumer is woken to wait for the item, then it gives up.
+	dq.PushAfter(42, time.Second)
+
+	clock.WaitTimers(1)
+	cancel()
+
+	if err := <-cancelled; err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	// The other consumer takes over the wait.
+	clock.WaitTimers(2)
+	clock.Advance(time.Second)
+
+	if v := <-result; v != 42 {
+		t.Fatalf("expected %d, got %d", 42, v)
+	}
+}
+
+func TestDelayQueue_Close(t *testing.T) {
+	clock := newFakeClock()
+	dq := NewDelayQueue[int](1, clock)
+
+	done := make(chan error)
+
+	go func() {
+		_, err := dq.PopWait(context.Background())
+		done <- err
+	}()
+
+	dq.Close()
+
+	if err := <-done; err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+
+	if err := dq.PushAfter(1, 0); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+
+	if err := dq.Close(); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+}
+
+func TestDelayQueue_Drain(t *testing.T) {
+	clock := newFakeClock()
+	dq := NewDelayQueue[int](1, clock)
+
+	dq.PushAfter(2, 2*time.Second)
+	dq.PushAfter(1, time.Second)
+
+	if expected, actual := []int{1, 2}, dq.Drain(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	if !dq.Empty() {
+		t.Fatalf("expected empty queue, got non-empty")
+	}
+}
+
+func TestDelayQueue_Invalid(t *testing.T) {
+	dq := &DelayQueue[int]{}
+
+	if err := dq.Push(1, time.Time{}); err != ErrInvalidQueue {
+		t.Fatalf("expected %v, got %v", ErrInvalidQueue, err)
+	}
+
+	if _, err := dq.PopWait(context.Background()); err != ErrInvalidQueue {
+		t.Fatalf("expected %v, got %v", ErrInvalidQueue, err)
+	}
+}
+
+// waitConsumers blocks until n consumers are blocked in PopWait.
+func waitConsumers[T any](dq *DelayQueue[T], n int) {
+	for {
+		dq.mu.Lock()
+		l := dq.consumers.len()
+		dq.mu.Unlock()
+
+		if l >= n {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
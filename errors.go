@@ -10,4 +10,37 @@ var (
 
 	// ErrInvalidQueue is returned when an non-applicable operation was called on a nil queue.
 	ErrInvalidQueue = errors.New("invalid queue")
+
+	// ErrClosed is returned when an item was added to a closed queue, or a closed queue has no items left.
+	ErrClosed = errors.New("closed queue")
+
+	// ErrFull is returned when an item was added to a bounded queue which has no room left.
+	ErrFull = errors.New("full queue")
+
+	// ErrDropped is returned when an item was added to a full queue and dropped by its overflow policy.
+	ErrDropped = errors.New("item dropped by overflow policy")
+
+	// ErrNotInQueue is returned when an operation was called with an item which does not belong to the queue.
+	ErrNotInQueue = errors.New("item not in queue")
+
+	// ErrNotReady is returned when a delay queue has items but none of them is ready yet.
+	ErrNotReady = errors.New("no ready items")
+
+	// ErrWeightTooLarge is returned when more resources were requested from a semaphore than its size.
+	ErrWeightTooLarge = errors.New("weight exceeds semaphore size")
+
+	// ErrNegativeWeight is returned when a negative number of resources was acquired from or released to a semaphore.
+	ErrNegativeWeight = errors.New("negative semaphore weight")
+
+	// ErrOverRelease is returned when more resources were released to a semaphore than held.
+	ErrOverRelease = errors.New("semaphore released more than held")
+
+	// ErrCostTooLarge is returned when an entry was added to a cache which costs more than its capacity.
+	ErrCostTooLarge = errors.New("cost exceeds cache capacity")
+
+	// ErrInvalidCost is returned when an entry was added to a cache with a cost less than 1.
+	ErrInvalidCost = errors.New("invalid cache entry cost")
+
+	// ErrInvalidInterval is returned when a periodic task was started with a non-positive interval.
+	ErrInvalidInterval = errors.New("invalid interval")
 )
@@ -39,7 +39,9 @@ func main() {
 		}
 	}
 
-	pqueue := xtypes.NewPriorityQueue(len(tasks))
+	pqueue := xtypes.NewPriorityQueueOf(len(tasks), func(a, b *work) bool {
+		return a.preference < b.preference
+	})
 
 	for _, t := range tasks {
 		if err := pqueue.Push(t); err != nil {
@@ -52,7 +54,7 @@ func main() {
 		log.Printf("failed to do work: %s", err)
 	}
 
-	queue := xtypes.NewQueue(len(tasks))
+	queue := xtypes.NewQueueOf[*work](len(tasks))
 
 	for _, t := range tasks {
 		if err := queue.Push(t); err != nil {
@@ -66,9 +68,9 @@ func main() {
 	}
 }
 
-func doWorkInOrder(queue *xtypes.PriorityQueue) error {
+func doWorkInOrder(queue *xtypes.PriorityQueueOf[*work]) error {
 	for !queue.Empty() {
-		item, err := queue.Pop()
+		task, err := queue.Pop()
 		if err != nil {
 			if err == xtypes.ErrEmptyQueue {
 				log.Printf("empty queue")
@@ -80,19 +82,13 @@ func doWorkInOrder(queue *xtypes.PriorityQueue) error {
 			continue
 		}
 
-		task, ok := item.(*work)
-		if !ok {
-			log.Printf("failed to get task: invalid type")
-			continue
-		}
-
 		log.Printf("working in order: %s", task)
 	}
 
 	return nil
 }
 
-func doWorkInParallel(queue *xtypes.Queue) error {
+func doWorkInParallel(queue *xtypes.QueueOf[*work]) error {
 	items, err := queue.Get(queue.Len())
 	if err != nil {
 		if err == xtypes.ErrEmptyQueue {
@@ -106,13 +102,7 @@ func doWorkInParallel(queue *xtypes.Queue) error {
 
 	var wg sync.WaitGroup
 
-	for _, v := range items {
-		task, ok := v.(*work)
-		if !ok {
-			log.Printf("failed to get task: invalid type")
-			continue
-		}
-
+	for _, task := range items {
 		sema.Acquire(1)
 		wg.Add(1)
 
@@ -129,7 +119,6 @@ func doWorkInParallel(queue *xtypes.Queue) error {
 }
 
 type work struct {
-	index       int
 	preference  int
 	description string
 }
@@ -137,15 +126,3 @@ type work struct {
 func (w work) String() string {
 	return fmt.Sprintf("task with %s", w.description)
 }
-
-func (w *work) Priority() int {
-	return w.preference
-}
-
-func (w *work) Index() int {
-	return w.index
-}
-
-func (w *work) SetIndex(idx int) {
-	w.index = idx
-}
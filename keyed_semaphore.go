@@ -0,0 +1,165 @@
+package xtypes
+
+import (
+	"context"
+	"sync"
+)
+
+// KeyedSemaphore limits concurrency per key, e.g. per tenant or per host, with an optional global cap.
+//
+// Each key gets its own WeightedSemaphore when it is first acquired,
+// the semaphore is freed once nothing is held or waited for on the key.
+// KeyedSemaphore MUST be created using constructor.
+type KeyedSemaphore[K comparable] struct {
+	mu     sync.Mutex // Protects keys.
+	size   int
+	keys   map[K]*keyedEntry
+	global *WeightedSemaphore // Nil if there is no global cap.
+}
+
+// keyedEntry is the semaphore of a key and its references.
+type keyedEntry struct {
+	sema    *WeightedSemaphore
+	held    int // Resources held.
+	waiting int // Requests in Acquire.
+}
+
+// NewKeyedSemaphore returns a new KeyedSemaphore allowing size resources per key and global resources in total.
+//
+// If global is not positive, there is no global cap.
+func NewKeyedSemaphore[K comparable](size, global int) *KeyedSemaphore[K] {
+	ks := &KeyedSemaphore[K]{
+		size: size,
+		keys: make(map[K]*keyedEntry),
+	}
+
+	if global > 0 {
+		ks.global = NewWeightedSemaphore(global)
+	}
+
+	return ks
+}
+
+// Acquire gets the n resources for the key, blocking until they are available both for the key
+// and globally or ctx is done.
+//
+// ErrWeightTooLarge is returned if n exceeds the per-key size or the global cap, ErrNegativeWeight if n is negative.
+// On cancellation nothing is held and ctx.Err() is returned.
+func (ks *KeyedSemaphore[K]) Acquire(ctx context.Context, key K, n int) error {
+	e := ks.ref(key)
+
+	if err := e.sema.Acquire(ctx, n); err != nil {
+		ks.unref(key, e, 0)
+
+		return err
+	}
+
+	// The key is acquired first, so a busy key does not hold global resources while waiting for its own.
+	if ks.global != nil {
+		if err := ks.global.Acquire(ctx, n); err != nil {
+			e.sema.Release(n)
+			ks.unref(key, e, 0)
+
+			return err
+		}
+	}
+
+	ks.unref(key, e, n)
+
+	return nil
+}
+
+// TryAcquire gets the n resources for the key without blocking.
+//
+// It returns false and takes nothing if the n resources are not available right away or n is negative.
+func (ks *KeyedSemaphore[K]) TryAcquire(key K, n int) bool {
+	e := ks.ref(key)
+
+	if !e.sema.TryAcquire(n) {
+		ks.unref(key, e, 0)
+
+		return false
+	}
+
+	if ks.global != nil && !ks.global.TryAcquire(n) {
+		e.sema.Release(n)
+		ks.unref(key, e, 0)
+
+		return false
+	}
+
+	ks.unref(key, e, n)
+
+	return true
+}
+
+// Release returns the n resources of the key.
+//
+// If more resources are released than held for the key, nothing is released and ErrOverRelease is returned.
+// ErrNegativeWeight is returned if n is negative.
+func (ks *KeyedSemaphore[K]) Release(key K, n int) error {
+	if n < 0 {
+		return ErrNegativeWeight
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	e, ok := ks.keys[key]
+	if !ok || n > e.held {
+		return ErrOverRelease
+	}
+
+	if ks.global != nil {
+		ks.global.Release(n)
+	}
+
+	e.sema.Release(n)
+	e.held -= n
+
+	ks.free(key, e)
+
+	return nil
+}
+
+// Len returns the number of keys which have resources held or waited for.
+func (ks *KeyedSemaphore[K]) Len() int {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	return len(ks.keys)
+}
+
+// ref returns the entry of the key, creating it if needed, and registers a request on it.
+func (ks *KeyedSemaphore[K]) ref(key K) *keyedEntry {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	e, ok := ks.keys[key]
+	if !ok {
+		e = &keyedEntry{sema: NewWeightedSemaphore(ks.size)}
+		ks.keys[key] = e
+	}
+
+	e.waiting++
+
+	return e
+}
+
+// unref unregisters a request which got n resources.
+func (ks *KeyedSemaphore[K]) unref(key K, e *keyedEntry, n int) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	e.waiting--
+	e.held += n
+
+	ks.free(key, e)
+}
+
+// free removes the entry of the key if it is not referenced.
+func (ks *KeyedSemaphore[K]) free(key K, e *keyedEntry) {
+	if e.held == 0 && e.waiting == 0 {
+		delete(ks.keys, key)
+	}
+}
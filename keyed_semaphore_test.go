@@ -0,0 +1,217 @@
+package xtypes
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewKeyedSemaphore(t *testing.T) {
+	ks := NewKeyedSemaphore[string](1, 0)
+	if ks == nil {
+		t.Fatal("failed to create semaphore")
+	}
+}
+
+func TestKeyedSemaphore_PerKey(t *testing.T) {
+	ks := NewKeyedSemaphore[string](2, 0)
+
+	if err := ks.Acquire(context.Background(), "a", 2); err != nil {
+		t.Fatalf("failed to acquire: %v", err)
+	}
+
+	if ks.TryAcquire("a", 1) {
+		t.Fatal("expected not to acquire")
+	}
+
+	// Other keys are not affected.
+	if !ks.TryAcquire("b", 2) {
+		t.Fatal("expected to acquire")
+	}
+
+	if err := ks.Acquire(context.Background(), "c", 3); err != ErrWeightTooLarge {
+		t.Fatalf("expected %v, got %v", ErrWeightTooLarge, err)
+	}
+
+	if l := ks.Len(); l != 2 {
+		t.Fatalf("expected %d, got %d", 2, l)
+	}
+
+	ks.Release("a", 1)
+
+	if !ks.TryAcquire("a", 1) {
+		t.Fatal("expected to acquire")
+	}
+}
+
+func TestKeyedSemaphore_Global(t *testing.T) {
+	ks := NewKeyedSemaphore[string](2, 3)
+
+	ks.Acquire(context.Background(), "a", 2)
+	ks.Acquire(context.Background(), "b", 1)
+
+	if ks.TryAcquire("b", 1) {
+		t.Fatal("expected not to acquire")
+	}
+
+	// Failed requests do not keep keys alive.
+	if ks.TryAcquire("c", 1) {
+		t.Fatal("expected not to acquire")
+	}
+
+	if l := ks.Len(); l != 2 {
+		t.Fatalf("expected %d, got %d", 2, l)
+	}
+
+	done := make(chan error)
+
+	go func() {
+		done <- ks.Acquire(context.Background(), "c", 1)
+	}()
+
+	select {
+	case err := <-done:
+		t.Fatalf("expected to block, got %v", err)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	ks.Release("a", 1)
+
+	if err := <-done; err != nil {
+		t.Fatalf("failed to acquire: %v", err)
+	}
+}
+
+func TestKeyedSemaphore_Cancel(t *testing.T) {
+	ks := NewKeyedSemaphore[string](1, 2)
+
+	ks.Acquire(context.Background(), "a", 1)
+	ks.Acquire(context.Background(), "b", 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Blocked by the global cap.
+	if err := ks.Acquire(ctx, "c", 1); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	// Blocked by the key.
+	if err := ks.Acquire(ctx, "a", 1); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	ks.Release("a", 1)
+	ks.Release("b", 1)
+
+	if l := ks.Len(); l != 0 {
+		t.Fatalf("expected %d, got %d", 0, l)
+	}
+
+	if !ks.TryAcquire("c", 1) || !ks.TryAcquire("a", 1) {
+		t.Fatal("expected to acquire")
+	}
+}
+
+func TestKeyedSemaphore_OverRelease(t *testing.T) {
+	ks := NewKeyedSemaphore[string](2, 0)
+
+	if err := ks.Release("a", 1); err != ErrOverRelease {
+		t.Fatalf("expected %v, got %v", ErrOverRelease, err)
+	}
+
+	ks.Acquire(context.Background(), "a", 1)
+
+	if err := ks.Release("a", 2); err != ErrOverRelease {
+		t.Fatalf("expected %v, got %v", ErrOverRelease, err)
+	}
+
+	if err := ks.Release("a", 1); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+
+	if l := ks.Len(); l != 0 {
+		t.Fatalf("expected %d, got %d", 0, l)
+	}
+}
+
+func TestKeyedSemaphore_NegativeWeight(t *testing.T) {
+	ks := NewKeyedSemaphore[string](1, 2)
+
+	if err := ks.Acquire(context.Background(), "a", -1); err != ErrNegativeWeight {
+		t.Fatalf("expected %v, got %v", ErrNegativeWeight, err)
+	}
+
+	if ks.TryAcquire("a", -1) {
+		t.Fatal("expected not to acquire a negative weight")
+	}
+
+	ks.Acquire(context.Background(), "a", 1)
+
+	if err := ks.Release("a", -1); err != ErrNegativeWeight {
+		t.Fatalf("expected %v, got %v", ErrNegativeWeight, err)
+	}
+
+	if ks.TryAcquire("a", 1) {
+		t.Fatal("expected not to acquire more than size")
+	}
+
+	if err := ks.Release("a", 1); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+
+	if l := ks.Len(); l != 0 {
+		t.Fatalf("expected %d, got %d", 0, l)
+	}
+}
+
+func TestKeyedSemaphore_Concurrent(t *testing.T) {
+	const (
+		perKey = 2
+		global = 5
+	)
+
+	ks := NewKeyedSemaphore[int](perKey, global)
+
+	var (
+		wg      sync.WaitGroup
+		total   atomic.Int64
+		perKeys [4]atomic.Int64
+	)
+
+	for i := 0; i < 16; i++ {
+		wg.Add(1)
+
+		go func(key int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				if err := ks.Acquire(context.Background(), key, 1); err != nil {
+					t.Errorf("failed to acquire: %v", err)
+					return
+				}
+
+				if v := total.Add(1); v > global {
+					t.Errorf("expected at most %d, got %d", global, v)
+				}
+
+				if v := perKeys[key].Add(1); v > perKey {
+					t.Errorf("expected at most %d, got %d", perKey, v)
+				}
+
+				perKeys[key].Add(-1)
+				total.Add(-1)
+
+				ks.Release(key, 1)
+			}
+		}(i % len(perKeys))
+	}
+
+	wg.Wait()
+
+	if l := ks.Len(); l != 0 {
+		t.Fatalf("expected %d, got %d", 0, l)
+	}
+}
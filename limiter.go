@@ -0,0 +1,310 @@
+package xtypes
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Outcome is the result of an operation guarded by a Limiter.
+type Outcome int
+
+const (
+	// OutcomeSuccess means the operation succeeded, its RTT is a valid sample.
+	OutcomeSuccess Outcome = iota
+
+	// OutcomeFailure means the operation failed for a reason unrelated to load, its RTT is ignored.
+	OutcomeFailure
+
+	// OutcomeDropped means the operation was dropped or timed out because of overload, the limit goes down.
+	OutcomeDropped
+)
+
+// LimitAlgorithm computes a concurrency limit from observed samples.
+//
+// Implementations are NOT required to be thread safe, Limiter serialises calls.
+type LimitAlgorithm interface {
+	// Limit returns the current limit.
+	Limit() int
+
+	// Update records a sample of an operation which took rtt while inflight operations were running,
+	// dropped reports whether the operation was dropped. It returns the new limit.
+	Update(rtt time.Duration, inflight int, dropped bool) int
+}
+
+// Limiter is a concurrency limiter which adjusts its limit from observed latency and outcomes.
+//
+// It is built on WeightedSemaphore, the limit computed by the algorithm is applied with SetLimit.
+// Limiter MUST be created using constructor.
+type Limiter struct {
+	mu       sync.Mutex // Protects algo.
+	algo     LimitAlgorithm
+	sema     *WeightedSemaphore
+	clock    Clock
+	inflight atomic.Int64
+}
+
+// NewLimiter returns a new Limiter driven by algo.
+//
+// If clock is nil, the system clock is used.
+func NewLimiter(algo LimitAlgorithm, clock Clock) *Limiter {
+	if clock == nil {
+		clock = SystemClock()
+	}
+
+	return &Limiter{
+		algo:  algo,
+		sema:  NewWeightedSemaphore(algo.Limit()),
+		clock: clock,
+	}
+}
+
+// Acquire gets a token, blocking until the number of inflight operations is below the limit or ctx is done.
+// On cancellation ctx.Err() is returned.
+func (l *Limiter) Acquire(ctx context.Context) (*LimiterToken, error) {
+	if err := l.sema.Acquire(ctx, 1); err != nil {
+		return nil, err
+	}
+
+	return l.token(), nil
+}
+
+// TryAcquire gets a token without blocking. It returns false if the limit has been reached.
+func (l *Limiter) TryAcquire() (*LimiterToken, bool) {
+	if !l.sema.TryAcquire(1) {
+		return nil, false
+	}
+
+	return l.token(), true
+}
+
+// Limit returns the current limit.
+func (l *Limiter) Limit() int {
+	return l.sema.Limit()
+}
+
+// Inflight returns the number of tokens which have not been released.
+func (l *Limiter) Inflight() int {
+	return int(l.inflight.Load())
+}
+
+// token starts a new operation.
+func (l *Limiter) token() *LimiterToken {
+	inflight := l.inflight.Add(1)
+
+	return &LimiterToken{
+		limiter:  l,
+		start:    l.clock.Now(),
+		inflight: int(inflight),
+	}
+}
+
+// release records the outcome of an operation and gives its slot back.
+func (l *Limiter) release(rtt time.Duration, inflight int, outcome Outcome) {
+	l.mu.Lock()
+
+	if outcome != OutcomeFailure {
+		l.sema.SetLimit(max(l.algo.Update(rtt, inflight, outcome == OutcomeDropped), 1))
+	}
+
+	l.mu.Unlock()
+
+	l.inflight.Add(-1)
+	l.sema.Release(1)
+}
+
+// LimiterToken is a slot of Limiter held by an operation.
+//
+// Exactly one of its methods must be called once the operation is over, subsequent calls are ignored.
+type LimiterToken struct {
+	limiter  *Limiter
+	start    time.Time
+	inflight int // Inflight operations when the token was acquired, including this one.
+	released atomic.Bool
+}
+
+// Success reports that the operation succeeded, the RTT is measured with the clock of the limiter.
+func (t *LimiterToken) Success() {
+	t.Report(OutcomeSuccess, t.limiter.clock.Now().Sub(t.start))
+}
+
+// Failure reports that the operation failed for a reason unrelated to load.
+func (t *LimiterToken) Failure() {
+	t.Report(OutcomeFailure, t.limiter.clock.Now().Sub(t.start))
+}
+
+// Dropped reports that the operation was dropped or timed out because of overload.
+func (t *LimiterToken) Dropped() {
+	t.Report(OutcomeDropped, t.limiter.clock.Now().Sub(t.start))
+}
+
+// Report reports the outcome of the operation with an RTT measured by the caller.
+func (t *LimiterToken) Report(outcome Outcome, rtt time.Duration) {
+	if !t.released.CompareAndSwap(false, true) {
+		return
+	}
+
+	t.limiter.release(rtt, t.inflight, outcome)
+}
+
+// AIMDConfig configures AIMDLimit. Zero fields take default values.
+type AIMDConfig struct {
+	Initial int           // Initial limit, 20 by default.
+	Min     int           // Minimum limit, 1 by default.
+	Max     int           // Maximum limit, 200 by default.
+	Backoff float64       // Multiplier applied on a drop, in (0, 1), 0.9 by default.
+	Timeout time.Duration // RTT above which a sample counts as a drop, no timeout by default.
+}
+
+// AIMDLimit is an additive-increase/multiplicative-decrease LimitAlgorithm.
+//
+// The limit grows by one after a successful sample taken while at least half of it was in use,
+// and is multiplied by the backoff after a drop or a timeout.
+type AIMDLimit struct {
+	cfg   AIMDConfig
+	limit float64
+}
+
+// NewAIMDLimit returns a new AIMDLimit.
+func NewAIMDLimit(cfg AIMDConfig) *AIMDLimit {
+	if cfg.Min <= 0 {
+		cfg.Min = 1
+	}
+
+	if cfg.Max <= 0 {
+		cfg.Max = 200
+	}
+
+	if cfg.Initial <= 0 {
+		cfg.Initial = 20
+	}
+
+	if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+		cfg.Backoff = 0.9
+	}
+
+	return &AIMDLimit{
+		cfg:   cfg,
+		limit: float64(clamp(cfg.Initial, cfg.Min, cfg.Max)),
+	}
+}
+
+// Limit implements LimitAlgorithm.
+func (a *AIMDLimit) Limit() int {
+	return int(a.limit)
+}
+
+// Update implements LimitAlgorithm.
+func (a *AIMDLimit) Update(rtt time.Duration, inflight int, dropped bool) int {
+	switch {
+	case dropped || (a.cfg.Timeout > 0 && rtt > a.cfg.Timeout):
+		a.limit = math.Floor(a.limit * a.cfg.Backoff)
+	case float64(inflight)*2 >= a.limit:
+		a.limit++
+	}
+
+	a.limit = float64(clamp(int(a.limit), a.cfg.Min, a.cfg.Max))
+
+	return int(a.limit)
+}
+
+// GradientConfig configures GradientLimit. Zero fields take default values.
+type GradientConfig struct {
+	Initial   int     // Initial limit, 20 by default.
+	Min       int     // Minimum limit, 1 by default.
+	Max       int     // Maximum limit, 200 by default.
+	Smoothing float64 // Weight of a new limit, in (0, 1], 0.2 by default.
+	Tolerance float64 // Ratio of the short RTT to the long RTT tolerated before the limit goes down, 1.5 by default.
+	Window    int     // Number of samples the long RTT is averaged over, 600 by default.
+}
+
+// GradientLimit is a LimitAlgorithm modelled on the gradient algorithm of Netflix's concurrency-limits.
+//
+// It compares the latest RTT with a long-term exponential average of RTTs.
+// While they are close the limit grows by a queue allowance of the square root of the limit,
+// once latency rises the limit shrinks proportionally to the gradient.
+type GradientLimit struct {
+	cfg     GradientConfig
+	limit   float64
+	longRTT float64 // Exponential average of RTT in nanoseconds, zero until the first sample.
+}
+
+// NewGradientLimit returns a new GradientLimit.
+func NewGradientLimit(cfg GradientConfig) *GradientLimit {
+	if cfg.Min <= 0 {
+		cfg.Min = 1
+	}
+
+	if cfg.Max <= 0 {
+		cfg.Max = 200
+	}
+
+	if cfg.Initial <= 0 {
+		cfg.Initial = 20
+	}
+
+	if cfg.Smoothing <= 0 || cfg.Smoothing > 1 {
+		cfg.Smoothing = 0.2
+	}
+
+	if cfg.Tolerance < 1 {
+		cfg.Tolerance = 1.5
+	}
+
+	if cfg.Window <= 0 {
+		cfg.Window = 600
+	}
+
+	return &GradientLimit{
+		cfg:   cfg,
+		limit: float64(clamp(cfg.Initial, cfg.Min, cfg.Max)),
+	}
+}
+
+// Limit implements LimitAlgorithm.
+func (g *GradientLimit) Limit() int {
+	return int(g.limit)
+}
+
+// Update implements LimitAlgorithm.
+func (g *GradientLimit) Update(rtt time.Duration, inflight int, dropped bool) int {
+	short := float64(rtt)
+	if short <= 0 {
+		return int(g.limit)
+	}
+
+	if g.longRTT == 0 {
+		g.longRTT = short
+	} else {
+		g.longRTT += (short - g.longRTT) * 2 / float64(g.cfg.Window+1)
+	}
+
+	// Let the long RTT recover quickly once latency goes back down.
+	if g.longRTT/short > 2 {
+		g.longRTT *= 0.95
+	}
+
+	// The limit is not used up, there is nothing to learn about it.
+	if !dropped && float64(inflight) < g.limit/2 {
+		return int(g.limit)
+	}
+
+	gradient := math.Max(0.5, math.Min(1, g.cfg.Tolerance*g.longRTT/short))
+	if dropped {
+		gradient = 0.5
+	}
+
+	limit := g.limit*gradient + math.Sqrt(g.limit)
+	limit = g.limit*(1-g.cfg.Smoothing) + limit*g.cfg.Smoothing
+
+	g.limit = math.Max(float64(g.cfg.Min), math.Min(float64(g.cfg.Max), limit))
+
+	return int(g.limit)
+}
+
+// clamp limits v to [lo, hi].
+func clamp(v, lo, hi int) int {
+	return max(lo, min(v, hi))
+}
@@ -0,0 +1,189 @@
+package xtypes
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestNewLimiter(t *testing.T) {
+	l := NewLimiter(NewAIMDLimit(AIMDConfig{Initial: 5}), nil)
+	if l == nil {
+		t.Fatal("failed to create limiter")
+	}
+
+	if actual := l.Limit(); actual != 5 {
+		t.Fatalf("expected %d, got %d", 5, actual)
+	}
+}
+
+func TestLimiter_Acquire(t *testing.T) {
+	l := NewLimiter(NewAIMDLimit(AIMDConfig{Initial: 2}), newFakeClock())
+
+	a, _ := l.TryAcquire()
+	b, err := l.Acquire(context.Background())
+	if err != nil {
+		t.Fatalf("failed to acquire: %v", err)
+	}
+
+	if _, ok := l.TryAcquire(); ok {
+		t.Fatal("expected not to acquire")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := l.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if actual := l.Inflight(); actual != 2 {
+		t.Fatalf("expected %d, got %d", 2, actual)
+	}
+
+	a.Failure()
+	b.Failure()
+
+	// A token is released once only.
+	b.Failure()
+
+	if actual := l.Inflight(); actual != 0 {
+		t.Fatalf("expected %d, got %d", 0, actual)
+	}
+
+	// Failures do not move the limit.
+	if actual := l.Limit(); actual != 2 {
+		t.Fatalf("expected %d, got %d", 2, actual)
+	}
+}
+
+func TestLimiter_AIMD(t *testing.T) {
+	clock := newFakeClock()
+	l := NewLimiter(NewAIMDLimit(AIMDConfig{Initial: 4, Max: 10, Backoff: 0.5}), clock)
+
+	// The limit grows while it is used up and latency stays low.
+	for i := 0; i < 5; i++ {
+		runLimiterRound(l, clock, 10*time.Millisecond, OutcomeSuccess)
+	}
+
+	if actual := l.Limit(); actual != 10 {
+		t.Fatalf("expected %d, got %d", 10, actual)
+	}
+
+	// A single drop halves it.
+	tok, _ := l.TryAcquire()
+	tok.Dropped()
+
+	if actual := l.Limit(); actual != 5 {
+		t.Fatalf("expected %d, got %d", 5, actual)
+	}
+
+	// Requests beyond the new limit are rejected.
+	for i := 0; i < 5; i++ {
+		if _, ok := l.TryAcquire(); !ok {
+			t.Fatalf("expected to acquire %d", i)
+		}
+	}
+
+	if _, ok := l.TryAcquire(); ok {
+		t.Fatal("expected not to acquire")
+	}
+}
+
+func TestLimiter_AIMDIdle(t *testing.T) {
+	clock := newFakeClock()
+	l := NewLimiter(NewAIMDLimit(AIMDConfig{Initial: 10}), clock)
+
+	// A single request at a time does not justify a higher limit.
+	for i := 0; i < 20; i++ {
+		tok, _ := l.TryAcquire()
+		clock.Advance(10 * time.Millisecond)
+		tok.Success()
+	}
+
+	if actual := l.Limit(); actual != 10 {
+		t.Fatalf("expected %d, got %d", 10, actual)
+	}
+}
+
+func TestAIMDLimit_Timeout(t *testing.T) {
+	a := NewAIMDLimit(AIMDConfig{Initial: 10, Min: 4, Backoff: 0.5, Timeout: 100 * time.Millisecond})
+
+	if actual := a.Update(50*time.Millisecond, 10, false); actual != 11 {
+		t.Fatalf("expected %d, got %d", 11, actual)
+	}
+
+	if actual := a.Update(200*time.Millisecond, 10, false); actual != 5 {
+		t.Fatalf("expected %d, got %d", 5, actual)
+	}
+
+	if actual := a.Update(200*time.Millisecond, 10, false); actual != 4 {
+		t.Fatalf("expected %d, got %d", 4, actual)
+	}
+}
+
+func TestLimiter_Gradient(t *testing.T) {
+	clock := newFakeClock()
+	l := NewLimiter(NewGradientLimit(GradientConfig{Initial: 10, Max: 50}), clock)
+
+	// Steady latency under full load: the limit climbs to the maximum.
+	for i := 0; i < 50; i++ {
+		runLimiterRound(l, clock, 10*time.Millisecond, OutcomeSuccess)
+	}
+
+	if actual := l.Limit(); actual != 50 {
+		t.Fatalf("expected %d, got %d", 50, actual)
+	}
+
+	// Latency jumps as a downstream queue builds up: the limit backs off.
+	for i := 0; i < 5; i++ {
+		runLimiterRound(l, clock, 50*time.Millisecond, OutcomeSuccess)
+	}
+
+	high := l.Limit()
+	if high >= 50 {
+		t.Fatalf("expected limit below %d, got %d", 50, high)
+	}
+
+	// Latency recovers: so does the limit.
+	for i := 0; i < 50; i++ {
+		runLimiterRound(l, clock, 10*time.Millisecond, OutcomeSuccess)
+	}
+
+	if actual := l.Limit(); actual <= high {
+		t.Fatalf("expected limit above %d, got %d", high, actual)
+	}
+}
+
+func TestGradientLimit_Dropped(t *testing.T) {
+	g := NewGradientLimit(GradientConfig{Initial: 100, Smoothing: 1})
+
+	if actual := g.Update(10*time.Millisecond, 1, true); actual != 60 {
+		t.Fatalf("expected %d, got %d", 60, actual)
+	}
+
+	// Samples without load change nothing.
+	if actual := g.Update(time.Second, 1, false); actual != 60 {
+		t.Fatalf("expected %d, got %d", 60, actual)
+	}
+}
+
+// runLimiterRound acquires all tokens the limiter allows, lets rtt pass and reports the outcome for each of them.
+func runLimiterRound(l *Limiter, clock *fakeClock, rtt time.Duration, outcome Outcome) {
+	var tokens []*LimiterToken
+
+	for {
+		tok, ok := l.TryAcquire()
+		if !ok {
+			break
+		}
+
+		tokens = append(tokens, tok)
+	}
+
+	clock.Advance(rtt)
+
+	for _, tok := range tokens {
+		tok.Report(outcome, clock.Now().Sub(tok.start))
+	}
+}
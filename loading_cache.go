@@ -0,0 +1,228 @@
+package xtypes
+
+import (
+	"context"
+	"fmt"
+	"runtime/debug"
+	"sync"
+	"time"
+)
+
+// LoadingConfig configures LoadingCacheOf. Zero fields disable the corresponding feature.
+type LoadingConfig struct {
+	Capacity     int           // Maximum number of entries, the least recently used are evicted, no limit by default.
+	TTL          time.Duration // Time to live of loaded values, they never expire by default.
+	ErrorTTL     time.Duration // Time to live of load errors, errors are not cached by default.
+	RefreshAhead time.Duration // Values are reloaded in the background when they are this close to expiry.
+}
+
+// LoadingCacheOf is a read-through cache which loads missing values with a loader function.
+//
+// Concurrent misses for the same key share a single load.
+// The load runs with the context of the caller which started it, but it is not cancelled with it,
+// each caller stops waiting when its own context is done.
+// If the loader panics, the panic is recovered and raised again in every caller waiting for the load,
+// nothing is stored then.
+// With RefreshAhead, a value close to expiry is returned as is while a fresh one is loaded in the background,
+// if the reload fails the current value is kept until it expires.
+// Time comes from the clock of the cache.
+// LoadingCacheOf MUST be created using constructor.
+type LoadingCacheOf[K comparable, V any] struct {
+	mu      sync.Mutex // Protects storage and calls.
+	storage Cache[K, loadEntry[V]]
+	calls   map[K]*loadCall[V]
+	loader  func(ctx context.Context, key K) (V, error)
+	clock   Clock
+	cfg     LoadingConfig
+}
+
+// loadEntry is a loaded value or error with its expiration time, zero if it never expires.
+type loadEntry[V any] struct {
+	value   V
+	err     error
+	expires time.Time
+}
+
+// loadCall is a load in progress.
+type loadCall[V any] struct {
+	done  chan struct{} // Closed when the load is over.
+	value V
+	err   error
+}
+
+// loadPanic is a panic of a loader, recovered to be raised again in the callers.
+type loadPanic struct {
+	value interface{}
+	stack []byte
+}
+
+// NewLoadingCacheOf returns a new LoadingCacheOf which loads values with the loader.
+//
+// If clock is nil, the system clock is used.
+func NewLoadingCacheOf[K comparable, V any](loader func(ctx context.Context, key K) (V, error), cfg LoadingConfig, clock Clock) *LoadingCacheOf[K, V] {
+	if clock == nil {
+		clock = SystemClock()
+	}
+
+	var storage Cache[K, loadEntry[V]]
+	if cfg.Capacity > 0 {
+		storage = NewLRUCacheOf[K, loadEntry[V]](int64(cfg.Capacity), nil, nil)
+	} else {
+		storage = NewSafeMapOf[K, loadEntry[V]](nil)
+	}
+
+	return &LoadingCacheOf[K, V]{
+		storage: storage,
+		calls:   make(map[K]*loadCall[V]),
+		loader:  loader,
+		clock:   clock,
+		cfg:     cfg,
+	}
+}
+
+// Get returns the value of the key, loading it if it is missing or expired.
+//
+// A cached load error is returned as is. On cancellation ctx.Err() is returned, the load goes on.
+// A panic of the loader is raised again in Get.
+func (c *LoadingCacheOf[K, V]) Get(ctx context.Context, key K) (V, error) {
+	now := c.clock.Now()
+
+	c.mu.Lock()
+
+	if e, ok := c.storage.Get(key); ok && !c.expired(e, now) {
+		if c.refreshing(e, now) {
+			if _, ok := c.calls[key]; !ok {
+				c.load(ctx, key)
+			}
+		}
+
+		c.mu.Unlock()
+
+		return e.value, e.err
+	}
+
+	call, ok := c.calls[key]
+	if !ok {
+		call = c.load(ctx, key)
+	}
+
+	c.mu.Unlock()
+
+	select {
+	case <-call.done:
+		if p, ok := call.err.(*loadPanic); ok {
+			panic(p)
+		}
+
+		return call.value, call.err
+	case <-ctx.Done():
+		var zero V
+
+		return zero, ctx.Err()
+	}
+}
+
+// Set stores the value of the key as if it has been loaded.
+func (c *LoadingCacheOf[K, V]) Set(key K, value V) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.storage.Set(key, c.entry(value, nil, c.clock.Now()))
+}
+
+// Del deletes the stored value or error of the key. A load in progress is not affected.
+func (c *LoadingCacheOf[K, V]) Del(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.storage.Del(key)
+}
+
+// Len returns count of stored entries, including errors and expired entries which have not been reloaded.
+func (c *LoadingCacheOf[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.storage.Len()
+}
+
+// load starts loading the key in the background and registers the call.
+func (c *LoadingCacheOf[K, V]) load(ctx context.Context, key K) *loadCall[V] {
+	call := &loadCall[V]{done: make(chan struct{})}
+	c.calls[key] = call
+
+	// Callers may give up, the load must not.
+	ctx = context.WithoutCancel(ctx)
+
+	go func() {
+		value, err := c.run(ctx, key)
+
+		c.mu.Lock()
+		defer c.mu.Unlock()
+
+		delete(c.calls, key)
+
+		call.value, call.err = value, err
+
+		now := c.clock.Now()
+
+		switch _, panicked := err.(*loadPanic); {
+		case panicked:
+			// Panics are not cached, the next caller loads again.
+		case err == nil:
+			c.storage.Set(key, c.entry(value, nil, now))
+		case c.cfg.ErrorTTL > 0:
+			// A failed refresh keeps the current value.
+			if e, ok := c.storage.Get(key); ok && e.err == nil && !c.expired(e, now) {
+				break
+			}
+
+			c.storage.Set(key, c.entry(value, err, now))
+		}
+
+		close(call.done)
+	}()
+
+	return call
+}
+
+// run calls the loader, a panic of the loader is recovered and returned as *loadPanic.
+func (c *LoadingCacheOf[K, V]) run(ctx context.Context, key K) (value V, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = &loadPanic{value: r, stack: debug.Stack()}
+		}
+	}()
+
+	return c.loader(ctx, key)
+}
+
+// entry returns an entry for the loaded value or error.
+func (c *LoadingCacheOf[K, V]) entry(value V, err error, now time.Time) loadEntry[V] {
+	ttl := c.cfg.TTL
+	if err != nil {
+		ttl = c.cfg.ErrorTTL
+	}
+
+	e := loadEntry[V]{value: value, err: err}
+	if ttl > 0 {
+		e.expires = now.Add(ttl)
+	}
+
+	return e
+}
+
+// expired returns true if the entry has expired by now.
+func (c *LoadingCacheOf[K, V]) expired(e loadEntry[V], now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// refreshing returns true if the entry is a value which is due to be reloaded ahead of expiry.
+func (c *LoadingCacheOf[K, V]) refreshing(e loadEntry[V], now time.Time) bool {
+	return c.cfg.RefreshAhead > 0 && e.err == nil && !e.expires.IsZero() && e.expires.Sub(now) <= c.cfg.RefreshAhead
+}
+
+// Error returns the panic value with the stack trace of the loader.
+func (p *loadPanic) Error() string {
+	return fmt.Sprintf("%v\n\n%s", p.value, p.stack)
+}
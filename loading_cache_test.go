@@ -0,0 +1,290 @@
+package xtypes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// errLoad is returned by failing loaders in tests.
+var errLoad = errors.New("load failed")
+
+// testLoader is a loader which counts its calls.
+type testLoader struct {
+	calls   atomic.Int64
+	fail    atomic.Bool
+	release chan struct{} // Blocks loads until closed if not nil.
+}
+
+func (l *testLoader) load(ctx context.Context, key string) (string, error) {
+	n := l.calls.Add(1)
+
+	if l.release != nil {
+		<-l.release
+	}
+
+	if l.fail.Load() {
+		return "", errLoad
+	}
+
+	return fmt.Sprintf("%s-%d", key, n), nil
+}
+
+func TestNewLoadingCacheOf(t *testing.T) {
+	l := &testLoader{}
+
+	c := NewLoadingCacheOf[string, string](l.load, LoadingConfig{}, nil)
+	if c == nil {
+		t.Fatal("failed to create cache")
+	}
+}
+
+func TestLoadingCacheOf_Get(t *testing.T) {
+	l := &testLoader{}
+	clock := newFakeClock()
+	c := NewLoadingCacheOf[string, string](l.load, LoadingConfig{TTL: time.Minute}, clock)
+
+	for i := 0; i < 3; i++ {
+		if v, err := c.Get(context.Background(), "a"); err != nil || v != "a-1" {
+			t.Fatalf("expected %s, got %s, %v", "a-1", v, err)
+		}
+	}
+
+	clock.Advance(time.Minute)
+
+	if v, _ := c.Get(context.Background(), "a"); v != "a-2" {
+		t.Fatalf("expected %s, got %s", "a-2", v)
+	}
+
+	c.Set("b", "manual")
+
+	if v, _ := c.Get(context.Background(), "b"); v != "manual" {
+		t.Fatalf("expected %s, got %s", "manual", v)
+	}
+
+	c.Del("a")
+
+	if v, _ := c.Get(context.Background(), "a"); v != "a-3" {
+		t.Fatalf("expected %s, got %s", "a-3", v)
+	}
+
+	if l := c.Len(); l != 2 {
+		t.Fatalf("expected %d, got %d", 2, l)
+	}
+}
+
+func TestLoadingCacheOf_Singleflight(t *testing.T) {
+	const callers = 10
+
+	l := &testLoader{release: make(chan struct{})}
+	c := NewLoadingCacheOf[string, string](l.load, LoadingConfig{}, nil)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			if v, err := c.Get(context.Background(), "a"); err != nil || v != "a-1" {
+				t.Errorf("expected %s, got %s, %v", "a-1", v, err)
+			}
+		}()
+	}
+
+	// Callers which come after the load get the cached value, either way there is a single load.
+	waitLoadStarted(l)
+	close(l.release)
+	wg.Wait()
+
+	if n := l.calls.Load(); n != 1 {
+		t.Fatalf("expected %d, got %d", 1, n)
+	}
+}
+
+func TestLoadingCacheOf_Cancel(t *testing.T) {
+	l := &testLoader{release: make(chan struct{})}
+	c := NewLoadingCacheOf[string, string](l.load, LoadingConfig{}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	done := make(chan error)
+
+	go func() {
+		_, err := c.Get(ctx, "a")
+		done <- err
+	}()
+
+	waitLoadStarted(l)
+	cancel()
+
+	if err := <-done; err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	// The load goes on and is shared with the next caller.
+	close(l.release)
+
+	if v, err := c.Get(context.Background(), "a"); err != nil || v != "a-1" {
+		t.Fatalf("expected %s, got %s, %v", "a-1", v, err)
+	}
+}
+
+func TestLoadingCacheOf_NegativeCaching(t *testing.T) {
+	l := &testLoader{}
+	clock := newFakeClock()
+	c := NewLoadingCacheOf[string, string](l.load, LoadingConfig{ErrorTTL: time.Second}, clock)
+
+	l.fail.Store(true)
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.Get(context.Background(), "a"); err != errLoad {
+			t.Fatalf("expected %v, got %v", errLoad, err)
+		}
+	}
+
+	if n := l.calls.Load(); n != 1 {
+		t.Fatalf("expected %d, got %d", 1, n)
+	}
+
+	l.fail.Store(false)
+	clock.Advance(time.Second)
+
+	if v, err := c.Get(context.Background(), "a"); err != nil || v != "a-2" {
+		t.Fatalf("expected %s, got %s, %v", "a-2", v, err)
+	}
+
+	// Without negative caching every call retries.
+	c = NewLoadingCacheOf[string, string](l.load, LoadingConfig{}, clock)
+
+	l.fail.Store(true)
+
+	c.Get(context.Background(), "b")
+	c.Get(context.Background(), "b")
+
+	if n := l.calls.Load(); n != 4 {
+		t.Fatalf("expected %d, got %d", 4, n)
+	}
+
+	if l := c.Len(); l != 0 {
+		t.Fatalf("expected %d, got %d", 0, l)
+	}
+}
+
+func TestLoadingCacheOf_RefreshAhead(t *testing.T) {
+	l := &testLoader{}
+	clock := newFakeClock()
+	c := NewLoadingCacheOf[string, string](l.load, LoadingConfig{
+		TTL:          10 * time.Minute,
+		ErrorTTL:     time.Minute,
+		RefreshAhead: 2 * time.Minute,
+	}, clock)
+
+	c.Get(context.Background(), "a")
+
+	clock.Advance(8 * time.Minute)
+
+	// The current value is returned while a new one is loaded.
+	if v, _ := c.Get(context.Background(), "a"); v != "a-1" {
+		t.Fatalf("expected %s, got %s", "a-1", v)
+	}
+
+	waitLoads(c)
+
+	if v, _ := c.Get(context.Background(), "a"); v != "a-2" {
+		t.Fatalf("expected %s, got %s", "a-2", v)
+	}
+
+	// A failed refresh keeps the current value.
+	l.fail.Store(true)
+	clock.Advance(9 * time.Minute)
+
+	c.Get(context.Background(), "a")
+	waitLoads(c)
+
+	if v, err := c.Get(context.Background(), "a"); err != nil || v != "a-2" {
+		t.Fatalf("expected %s, got %s, %v", "a-2", v, err)
+	}
+
+	waitLoads(c)
+
+	if n := l.calls.Load(); n != 4 {
+		t.Fatalf("expected %d, got %d", 4, n)
+	}
+}
+
+func TestLoadingCacheOf_Panic(t *testing.T) {
+	var calls atomic.Int64
+
+	load := func(ctx context.Context, key string) (string, error) {
+		if calls.Add(1) == 1 {
+			panic("boom")
+		}
+
+		return key, nil
+	}
+
+	c := NewLoadingCacheOf[string, string](load, LoadingConfig{ErrorTTL: time.Minute}, nil)
+
+	func() {
+		defer func() {
+			p, ok := recover().(*loadPanic)
+			if !ok || p.value != "boom" {
+				t.Fatalf("expected %v, got %v", "boom", p)
+			}
+		}()
+
+		c.Get(context.Background(), "a")
+	}()
+
+	// The panic is not cached.
+	if v, err := c.Get(context.Background(), "a"); err != nil || v != "a" {
+		t.Fatalf("expected %s, got %s, %v", "a", v, err)
+	}
+}
+
+func TestLoadingCacheOf_Capacity(t *testing.T) {
+	l := &testLoader{}
+	c := NewLoadingCacheOf[string, string](l.load, LoadingConfig{Capacity: 2}, nil)
+
+	c.Get(context.Background(), "a")
+	c.Get(context.Background(), "b")
+	c.Get(context.Background(), "a")
+	c.Get(context.Background(), "c")
+
+	if l := c.Len(); l != 2 {
+		t.Fatalf("expected %d, got %d", 2, l)
+	}
+
+	// The least recently used key has been evicted.
+	if v, _ := c.Get(context.Background(), "b"); v != "b-4" {
+		t.Fatalf("expected %s, got %s", "b-4", v)
+	}
+}
+
+// waitLoadStarted blocks until the loader has been called.
+func waitLoadStarted(l *testLoader) {
+	for l.calls.Load() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// waitLoads blocks until no loads are in progress.
+func waitLoads[K comparable, V any](c *LoadingCacheOf[K, V]) {
+	for {
+		c.mu.Lock()
+		l := len(c.calls)
+		c.mu.Unlock()
+
+		if l == 0 {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}
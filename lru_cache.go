@@ -0,0 +1,190 @@
+package xtypes
+
+import (
+	"container/list"
+	"sync"
+)
+
+// LRUCacheOf is a size-bounded cache which evicts the least recently used entries.
+//
+// The size of an entry is given by the cost function, every entry costs 1 without it,
+// so the capacity is either a number of entries or, e.g., a number of bytes.
+// Entries evicted to make room are passed to the eviction callback, outside of the lock.
+// Keys returns keys from the most to the least recently used.
+// LRUCacheOf MUST be created using constructor.
+type LRUCacheOf[K comparable, V any] struct {
+	mu       sync.Mutex // Protects fields below.
+	items    map[K]*list.Element
+	order    list.List // Of *cacheEntry, the most recently used first.
+	capacity int64
+	used     int64
+	cost     func(key K, value V) int64
+	onEvict  func(key K, value V)
+}
+
+// cacheEntry is an entry of a cache.
+type cacheEntry[K comparable, V any] struct {
+	key   K
+	value V
+	cost  int64
+}
+
+// NewLRUCacheOf returns a ready to use instance of LRUCacheOf.
+//
+// A non-positive capacity means the cache is not bounded.
+// The cost and onEvict functions can be nil, costs MUST be positive.
+func NewLRUCacheOf[K comparable, V any](capacity int64, cost func(key K, value V) int64, onEvict func(key K, value V)) *LRUCacheOf[K, V] {
+	return &LRUCacheOf[K, V]{
+		items:    make(map[K]*list.Element),
+		capacity: capacity,
+		cost:     cost,
+		onEvict:  onEvict,
+	}
+}
+
+// Get returns object and marks it as the most recently used.
+func (c *LRUCacheOf[K, V]) Get(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		var zero V
+
+		return zero, false
+	}
+
+	c.order.MoveToFront(e)
+
+	return e.Value.(*cacheEntry[K, V]).value, true
+}
+
+// Peek returns object without changing its recency.
+func (c *LRUCacheOf[K, V]) Peek(key K) (V, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.items[key]
+	if !ok {
+		var zero V
+
+		return zero, false
+	}
+
+	return e.Value.(*cacheEntry[K, V]).value, true
+}
+
+// Set sets the object as the most recently used, evicting the least recently used entries if needed.
+//
+// ErrCostTooLarge is returned and nothing is changed if the object alone costs more than the capacity,
+// ErrInvalidCost if its cost is less than 1.
+func (c *LRUCacheOf[K, V]) Set(key K, value V) error {
+	var cost int64 = 1
+	if c.cost != nil {
+		cost = c.cost(key, value)
+	}
+
+	if cost < 1 {
+		return ErrInvalidCost
+	}
+
+	c.mu.Lock()
+
+	if c.capacity > 0 && cost > c.capacity {
+		c.mu.Unlock()
+
+		return ErrCostTooLarge
+	}
+
+	if e, ok := c.items[key]; ok {
+		entry := e.Value.(*cacheEntry[K, V])
+
+		c.used += cost - entry.cost
+		entry.value, entry.cost = value, cost
+
+		c.order.MoveToFront(e)
+	} else {
+		c.items[key] = c.order.PushFront(&cacheEntry[K, V]{key: key, value: value, cost: cost})
+		c.used += cost
+	}
+
+	evicted := c.evict()
+
+	c.mu.Unlock()
+
+	c.notify(evicted)
+
+	return nil
+}
+
+// Del deletes the object. The eviction callback is not called.
+func (c *LRUCacheOf[K, V]) Del(key K) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.items[key]; ok {
+		c.remove(e)
+	}
+}
+
+// Len returns count of elements in the cache.
+func (c *LRUCacheOf[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.items)
+}
+
+// Cost returns the total cost of elements in the cache.
+func (c *LRUCacheOf[K, V]) Cost() int64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.used
+}
+
+// Keys returns all the keys as a slice, from the most to the least recently used.
+func (c *LRUCacheOf[K, V]) Keys() []K {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	keys := make([]K, 0, len(c.items))
+
+	for e := c.order.Front(); e != nil; e = e.Next() {
+		keys = append(keys, e.Value.(*cacheEntry[K, V]).key)
+	}
+
+	return keys
+}
+
+// evict removes the least recently used entries until the cache fits its capacity and returns them.
+func (c *LRUCacheOf[K, V]) evict() []*cacheEntry[K, V] {
+	var evicted []*cacheEntry[K, V]
+
+	for c.capacity > 0 && c.used > c.capacity {
+		evicted = append(evicted, c.remove(c.order.Back()))
+	}
+
+	return evicted
+}
+
+// remove removes the entry from the cache.
+func (c *LRUCacheOf[K, V]) remove(e *list.Element) *cacheEntry[K, V] {
+	entry := c.order.Remove(e).(*cacheEntry[K, V])
+
+	delete(c.items, entry.key)
+	c.used -= entry.cost
+
+	return entry
+}
+
+// notify passes evicted entries to the eviction callback.
+func (c *LRUCacheOf[K, V]) notify(evicted []*cacheEntry[K, V]) {
+	if c.onEvict == nil {
+		return
+	}
+
+	for _, entry := range evicted {
+		c.onEvict(entry.key, entry.value)
+	}
+}
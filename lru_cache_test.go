@@ -0,0 +1,207 @@
+package xtypes
+
+import (
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestNewLRUCacheOf(t *testing.T) {
+	c := NewLRUCacheOf[string, int](1, nil, nil)
+	if c == nil {
+		t.Fatalf("failed to create cache")
+	}
+}
+
+func TestLRUCacheOf_EvictionOrder(t *testing.T) {
+	var evicted []string
+
+	c := NewLRUCacheOf[string, int](3, nil, func(key string, value int) {
+		evicted = append(evicted, key)
+	})
+
+	c.Set("a", 1)
+	c.Set("b", 2)
+	c.Set("c", 3)
+
+	// Get makes "a" the most recently used, Peek does not touch "b".
+	c.Get("a")
+	c.Peek("b")
+
+	c.Set("d", 4)
+	c.Set("e", 5)
+
+	expected := []string{"b", "c"}
+	if !reflect.DeepEqual(expected, evicted) {
+		t.Fatalf("expected %#v, got %#v", expected, evicted)
+	}
+
+	expected = []string{"e", "d", "a"}
+	if actual := c.Keys(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+
+	// Updating a key makes it the most recently used without evictions.
+	c.Set("a", 10)
+
+	expected = []string{"a", "e", "d"}
+	if actual := c.Keys(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+
+	if v, ok := c.Get("a"); !ok || v != 10 {
+		t.Fatalf("expected %d, got %d", 10, v)
+	}
+
+	// Deleted entries are not reported as evicted.
+	c.Del("d")
+
+	if l := c.Len(); l != 2 {
+		t.Fatalf("expected %d, got %d", 2, l)
+	}
+
+	if l := len(evicted); l != 2 {
+		t.Fatalf("expected %d evicted, got %d", 2, l)
+	}
+}
+
+func TestLRUCacheOf_Cost(t *testing.T) {
+	var evicted []string
+
+	c := NewLRUCacheOf[string, string](10, func(key, value string) int64 {
+		return int64(len(value))
+	}, func(key, value string) {
+		evicted = append(evicted, key)
+	})
+
+	c.Set("a", "xxxx")
+	c.Set("b", "xxxx")
+
+	if cost := c.Cost(); cost != 8 {
+		t.Fatalf("expected %d, got %d", 8, cost)
+	}
+
+	// A single large entry evicts several small ones.
+	c.Set("c", "xxxxxxxx")
+
+	expected := []string{"a", "b"}
+	if !reflect.DeepEqual(expected, evicted) {
+		t.Fatalf("expected %#v, got %#v", expected, evicted)
+	}
+
+	if err := c.Set("d", "xxxxxxxxxxx"); err != ErrCostTooLarge {
+		t.Fatalf("expected %v, got %v", ErrCostTooLarge, err)
+	}
+
+	// Growing an entry counts too.
+	c.Set("e", "xx")
+	c.Set("c", "xxxxxxxxx")
+
+	expected = []string{"a", "b", "e"}
+	if !reflect.DeepEqual(expected, evicted) {
+		t.Fatalf("expected %#v, got %#v", expected, evicted)
+	}
+
+	if cost := c.Cost(); cost != 9 {
+		t.Fatalf("expected %d, got %d", 9, cost)
+	}
+
+	// Entries which cost nothing would not be bounded, negative costs would skew the total.
+	if err := c.Set("f", ""); err != ErrInvalidCost {
+		t.Fatalf("expected %v, got %v", ErrInvalidCost, err)
+	}
+
+	if _, ok := c.Peek("f"); ok || c.Cost() != 9 {
+		t.Fatalf("expected %d, got %d", 9, c.Cost())
+	}
+}
+
+func TestLRUCacheOf_NegativeCost(t *testing.T) {
+	c := NewLRUCacheOf[string, int64](10, func(key string, value int64) int64 {
+		return value
+	}, nil)
+
+	if err := c.Set("a", -5); err != ErrInvalidCost {
+		t.Fatalf("expected %v, got %v", ErrInvalidCost, err)
+	}
+
+	if cost := c.Cost(); cost != 0 {
+		t.Fatalf("expected %d, got %d", 0, cost)
+	}
+}
+
+func TestLRUCacheOf_Unbounded(t *testing.T) {
+	c := NewLRUCacheOf[int, int](0, nil, nil)
+
+	for i := 0; i < 100; i++ {
+		c.Set(i, i)
+	}
+
+	if l := c.Len(); l != 100 {
+		t.Fatalf("expected %d, got %d", 100, l)
+	}
+}
+
+func TestLRUCacheOf_Concurrent(t *testing.T) {
+	c := NewLRUCacheOf[int, int](16, nil, func(key, value int) {})
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for j := 0; j < 1000; j++ {
+				k := (w*1000 + j) % 64
+
+				c.Set(k, k)
+
+				if v, ok := c.Get(k); ok && v != k {
+					t.Errorf("expected %d, got %d", k, v)
+					return
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if l := c.Len(); l != 16 {
+		t.Fatalf("expected %d, got %d", 16, l)
+	}
+}
+
+// Benchmarks.
+
+func BenchmarkLRUCacheOf_SetGet(b *testing.B) {
+	c := NewLRUCacheOf[string, interface{}](benchMapKeys/2, nil, nil)
+
+	benchmarkSetGet(b, c.Set, c.Get)
+}
+
+func BenchmarkSafeMap_SetGet(b *testing.B) {
+	sm := NewSafeMap()
+
+	benchmarkSetGet(b, sm.Set, sm.Get)
+}
+
+// benchmarkSetGet sets and gets keys of a working set twice as large as the benchmarked caches.
+func benchmarkSetGet(b *testing.B, set func(key string, value interface{}) error, get func(key string) (interface{}, bool)) {
+	keys := make([]string, benchMapKeys)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		k := keys[i%len(keys)]
+
+		if _, ok := get(k); !ok {
+			set(k, i)
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package xtypes
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// MapEventType is the kind of a change of a map.
+type MapEventType int
+
+const (
+	// MapEventSet means a key has been set.
+	MapEventSet MapEventType = iota
+
+	// MapEventDel means a key has been deleted.
+	MapEventDel
+)
+
+// String returns the name of the event type.
+func (t MapEventType) String() string {
+	switch t {
+	case MapEventSet:
+		return "set"
+	case MapEventDel:
+		return "del"
+	default:
+		return "unknown"
+	}
+}
+
+// MapEvent is a change of a key of a map.
+type MapEvent[K comparable, V any] struct {
+	Type   MapEventType
+	Key    K
+	Old    V    // Value before the change, if HadOld is true.
+	New    V    // Value after the change, for MapEventSet.
+	HadOld bool // Whether the key had a value before the change.
+}
+
+// MapWatch is a subscription to changes of a map.
+//
+// Events are delivered in the order the changes have been made, through a buffered channel.
+// They are sent after the lock of the map is released, so a subscriber may read the map while handling them.
+// When the buffer is full, the overflow policy of the watch decides what happens:
+// OverflowBlock blocks writers of the map until there is room, so a slow subscriber slows the map down,
+// and a subscriber with this policy MUST NOT modify the map as it would wait for itself;
+// OverflowDropOldest drops the oldest buffered event; other policies drop the new event.
+// Dropped events are counted.
+// The watch ends when its context is done or Stop is called, the channel is closed then.
+type MapWatch[K comparable, V any] struct {
+	ctx     context.Context
+	stop    context.CancelFunc
+	match   func(key K) bool
+	events  chan MapEvent[K, V]
+	policy  OverflowPolicy
+	dropped atomic.Uint64
+	closed  bool // Guarded by sendMu of the map.
+}
+
+// watchEvent is an event waiting to be sent to a watch.
+type watchEvent[K comparable, V any] struct {
+	w *MapWatch[K, V]
+	e MapEvent[K, V]
+}
+
+// WatchMatch subscribes to changes of keys for which match returns true until ctx is done or the watch is stopped.
+//
+// The size is the number of events buffered for a slow subscriber, at least 1.
+func (s *SafeMapOf[K, V]) WatchMatch(ctx context.Context, match func(key K) bool, size int, policy OverflowPolicy) *MapWatch[K, V] {
+	ctx, stop := context.WithCancel(ctx)
+
+	w := &MapWatch[K, V]{
+		ctx:    ctx,
+		stop:   stop,
+		match:  match,
+		events: make(chan MapEvent[K, V], max(size, 1)),
+		policy: policy,
+	}
+
+	s.mu.Lock()
+
+	if s.watchers == nil {
+		s.watchers = make(map[*MapWatch[K, V]]struct{})
+		s.sendCond = sync.NewCond(&s.sendMu)
+	}
+
+	s.watchers[w] = struct{}{}
+
+	s.mu.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		s.mu.Lock()
+		delete(s.watchers, w)
+		s.mu.Unlock()
+
+		// Events queued before the watch has been removed may still be in delivery.
+		s.sendMu.Lock()
+		defer s.sendMu.Unlock()
+
+		w.closed = true
+		close(w.events)
+	}()
+
+	return w
+}
+
+// Watch subscribes to changes of keys with the prefix until ctx is done or the watch is stopped.
+//
+// The size is the number of events buffered for a slow subscriber, at least 1.
+func (s *SafeMap) Watch(ctx context.Context, prefix string, size int, policy OverflowPolicy) *MapWatch[string, interface{}] {
+	return s.WatchMatch(ctx, func(key string) bool {
+		return strings.HasPrefix(key, prefix)
+	}, size, policy)
+}
+
+// C returns the channel of events. It is closed when the watch ends.
+func (w *MapWatch[K, V]) C() <-chan MapEvent[K, V] {
+	return w.events
+}
+
+// Each calls fn for every event until the watch ends.
+func (w *MapWatch[K, V]) Each(fn func(e MapEvent[K, V])) {
+	for e := range w.events {
+		fn(e)
+	}
+}
+
+// Dropped returns the number of events dropped by the overflow policy.
+func (w *MapWatch[K, V]) Dropped() uint64 {
+	return w.dropped.Load()
+}
+
+// Stop ends the watch. Events already buffered are still delivered, then the channel is closed.
+// Stopping a stopped watch does nothing.
+func (w *MapWatch[K, V]) Stop() {
+	w.stop()
+}
+
+// send delivers the event applying the overflow policy.
+// It MUST be called with sendMu of the map held.
+func (w *MapWatch[K, V]) send(e MapEvent[K, V]) {
+	if w.closed || w.ctx.Err() != nil {
+		return
+	}
+
+	select {
+	case w.events <- e:
+		return
+	default:
+	}
+
+	switch w.policy {
+	case OverflowBlock:
+		select {
+		case w.events <- e:
+		case <-w.ctx.Done():
+		}
+	case OverflowDropOldest:
+		// The subscriber may take the oldest event in the meantime, either way there is room afterwards.
+		select {
+		case <-w.events:
+			w.dropped.Add(1)
+		default:
+		}
+
+		w.events <- e
+	default:
+		w.dropped.Add(1)
+	}
+}
+
+// queue adds the event for the matching watchers, it is sent when the map is unlocked.
+// It MUST be called with the lock of the map held.
+func (s *SafeMapOf[K, V]) queue(e MapEvent[K, V]) {
+	for w := range s.watchers {
+		if w.ctx.Err() == nil && w.match(e.Key) {
+			s.pending = append(s.pending, watchEvent[K, V]{w: w, e: e})
+		}
+	}
+}
+
+// unlock releases the lock of the map and sends the queued events.
+//
+// Each change with events takes a turn under the lock and waits for it after the lock is released,
+// so events are delivered in the order of changes while a blocked delivery never holds the map locked.
+func (s *SafeMapOf[K, V]) unlock() {
+	if len(s.pending) == 0 {
+		s.mu.Unlock()
+
+		return
+	}
+
+	pending := s.pending
+	s.pending = nil
+
+	turn := s.ticket
+	s.ticket++
+
+	s.mu.Unlock()
+
+	s.sendMu.Lock()
+	defer s.sendMu.Unlock()
+
+	for s.sent != turn {
+		s.sendCond.Wait()
+	}
+
+	for _, p := range pending {
+		p.w.send(p.e)
+	}
+
+	s.sent++
+	s.sendCond.Broadcast()
+}
@@ -0,0 +1,281 @@
+package xtypes
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSafeMap_Watch(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sm := NewSafeMap()
+	sm.Set("app.old", 0)
+
+	w := sm.Watch(ctx, "app.", 16, OverflowReject)
+
+	sm.Set("app.a", 1)
+	sm.Set("other", 1)
+	sm.Set("app.a", 2)
+	sm.Del("app.missing")
+	sm.Update("app.a", func(old interface{}, ok bool) (interface{}, bool) {
+		return old, false
+	})
+	sm.GetOrSet("app.b", 3)
+	sm.Drain()
+
+	expected := []MapEvent[string, interface{}]{
+		{Type: MapEventSet, Key: "app.a", New: 1},
+		{Type: MapEventSet, Key: "app.a", Old: 1, New: 2, HadOld: true},
+		{Type: MapEventDel, Key: "app.a", Old: 2, HadOld: true},
+		{Type: MapEventSet, Key: "app.b", New: 3},
+		{Type: MapEventDel, Key: "app.b", Old: 3, HadOld: true},
+		{Type: MapEventDel, Key: "app.old", Old: 0, HadOld: true},
+	}
+
+	for i, e := range expected {
+		if actual := <-w.C(); !reflect.DeepEqual(e, actual) {
+			t.Fatalf("event %d: expected %+v, got %+v", i, e, actual)
+		}
+	}
+
+	cancel()
+
+	if _, ok := <-w.C(); ok {
+		t.Fatal("expected closed channel")
+	}
+
+	// Changes after the end of the watch are not delivered.
+	sm.Set("app.c", 4)
+
+	if d := w.Dropped(); d != 0 {
+		t.Fatalf("expected %d, got %d", 0, d)
+	}
+}
+
+func TestSafeMapOf_WatchEach(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sm := NewSafeMapOf[int, int](nil)
+	w := sm.WatchMatch(ctx, func(key int) bool { return key%2 == 0 }, 1, OverflowBlock)
+
+	done := make(chan []int)
+
+	go func() {
+		var keys []int
+
+		w.Each(func(e MapEvent[int, int]) {
+			keys = append(keys, e.Key)
+		})
+
+		done <- keys
+	}()
+
+	for i := 0; i < 10; i++ {
+		sm.Set(i, i)
+	}
+
+	// Events sent before the end of the watch are still delivered.
+	cancel()
+
+	expected := []int{0, 2, 4, 6, 8}
+	if actual := <-done; !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestMapWatch_Overflow(t *testing.T) {
+	for _, tc := range []struct {
+		policy   OverflowPolicy
+		expected []int
+	}{
+		{OverflowReject, []int{0, 1}},
+		{OverflowEvictLowest, []int{0, 1}},
+		{OverflowDropOldest, []int{2, 3}},
+	} {
+		t.Run(tc.policy.String(), func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			sm := NewSafeMapOf[int, int](nil)
+			w := sm.WatchMatch(ctx, func(int) bool { return true }, 2, tc.policy)
+
+			for i := 0; i < 4; i++ {
+				sm.Set(i, i)
+			}
+
+			if d := w.Dropped(); d != 2 {
+				t.Fatalf("expected %d, got %d", 2, d)
+			}
+
+			for _, k := range tc.expected {
+				if e := <-w.C(); e.Key != k {
+					t.Fatalf("expected %d, got %d", k, e.Key)
+				}
+			}
+		})
+	}
+}
+
+func TestMapWatch_BlockCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	sm := NewSafeMapOf[int, int](nil)
+	w := sm.WatchMatch(ctx, func(int) bool { return true }, 1, OverflowBlock)
+
+	sm.Set(0, 0)
+
+	done := make(chan struct{})
+
+	go func() {
+		sm.Set(1, 1)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("expected writer to block")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	// The end of the watch releases the writer.
+	cancel()
+	<-done
+
+	for range w.C() {
+	}
+
+	if v, ok := sm.Get(1); !ok || v != 1 {
+		t.Fatalf("expected %d, got %d", 1, v)
+	}
+}
+
+func TestMapWatch_BlockRead(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sm := NewSafeMapOf[int, int](nil)
+	w := sm.WatchMatch(ctx, func(int) bool { return true }, 1, OverflowBlock)
+
+	done := make(chan int)
+
+	go func() {
+		sum := 0
+
+		// The subscriber reads the map while writers wait for room.
+		w.Each(func(e MapEvent[int, int]) {
+			v, _ := sm.Get(e.Key)
+			sum += v
+
+			if e.Key == 9 {
+				w.Stop()
+			}
+		})
+
+		done <- sum
+	}()
+
+	for i := 0; i < 10; i++ {
+		sm.Set(i, 1)
+	}
+
+	if sum := <-done; sum != 10 {
+		t.Fatalf("expected %d, got %d", 10, sum)
+	}
+}
+
+func TestMapWatch_BlockReadConcurrent(t *testing.T) {
+	const (
+		writers = 4
+		writes  = 200
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sm := NewSafeMapOf[int, int](nil)
+	w := sm.WatchMatch(ctx, func(int) bool { return true }, 1, OverflowBlock)
+
+	received := make(chan int)
+	gate := make(chan struct{})
+
+	go func() {
+		n := 0
+
+		// A slow subscriber reads the map once writers are blocked on it and waiting for each other.
+		w.Each(func(e MapEvent[int, int]) {
+			<-gate
+			sm.Get(e.Key)
+
+			n++
+		})
+
+		received <- n
+	}()
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+
+		go func(i int) {
+			defer wg.Done()
+
+			for j := 0; j < writes; j++ {
+				sm.Set(i*writes+j, j)
+			}
+		}(i)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	close(gate)
+
+	done := make(chan struct{})
+
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected writers to finish")
+	}
+
+	w.Stop()
+
+	if n := <-received; n != writers*writes {
+		t.Fatalf("expected %d, got %d", writers*writes, n)
+	}
+}
+
+func TestMapWatch_Stop(t *testing.T) {
+	sm := NewSafeMapOf[int, int](nil)
+	w := sm.WatchMatch(context.Background(), func(int) bool { return true }, 4, OverflowReject)
+
+	sm.Set(1, 1)
+
+	w.Stop()
+	w.Stop()
+
+	// Buffered events are delivered before the channel is closed.
+	if e := <-w.C(); e.Key != 1 {
+		t.Fatalf("expected %d, got %d", 1, e.Key)
+	}
+
+	if _, ok := <-w.C(); ok {
+		t.Fatal("expected closed channel")
+	}
+
+	sm.mu.RLock()
+	n := len(sm.watchers)
+	sm.mu.RUnlock()
+
+	if n != 0 {
+		t.Fatalf("expected %d watchers, got %d", 0, n)
+	}
+}
@@ -0,0 +1,349 @@
+package xtypes
+
+import (
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// skipMaxLevel is the maximum number of levels of a skip list, enough for 4^32 keys.
+const skipMaxLevel = 32
+
+// OrderedMapOf is a map which keeps its keys sorted, backed by a skip list.
+//
+// It is safe to use in concurrent mode, readers run concurrently.
+// Lookups and changes take O(log n), Keys and Drain take O(n) as keys are already in order.
+// Iteration callbacks run with the read lock held, they MUST NOT modify the map.
+// OrderedMapOf MUST be created using constructor.
+type OrderedMapOf[K comparable, V any] struct {
+	mu    sync.RWMutex // Protects fields below.
+	head  *skipNode[K, V]
+	tail  *skipNode[K, V]
+	level int
+	len   int
+	less  func(a, b K) bool
+	rnd   *rand.Rand
+}
+
+// skipNode is a node of a skip list, prev links the nodes of the bottom level backwards.
+type skipNode[K comparable, V any] struct {
+	key   K
+	value V
+	next  []*skipNode[K, V]
+	prev  *skipNode[K, V]
+}
+
+// NewOrderedMapOf returns a ready to use instance of OrderedMapOf with keys ordered by less.
+func NewOrderedMapOf[K comparable, V any](less func(a, b K) bool) *OrderedMapOf[K, V] {
+	m := &OrderedMapOf[K, V]{}
+	m.init(less)
+
+	return m
+}
+
+// Get returns object.
+func (m *OrderedMapOf[K, V]) Get(key K) (V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if n := m.ceiling(key, nil); n != nil && !m.less(key, n.key) {
+		return n.value, true
+	}
+
+	var zero V
+
+	return zero, false
+}
+
+// Set sets the object.
+func (m *OrderedMapOf[K, V]) Set(key K, value V) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var update [skipMaxLevel]*skipNode[K, V]
+
+	if n := m.ceiling(key, &update); n != nil && !m.less(key, n.key) {
+		n.value = value
+
+		return nil
+	}
+
+	lvl := m.randomLevel()
+
+	for ; m.level < lvl; m.level++ {
+		update[m.level] = m.head
+	}
+
+	n := &skipNode[K, V]{key: key, value: value, next: make([]*skipNode[K, V], lvl)}
+
+	for i := 0; i < lvl; i++ {
+		n.next[i] = update[i].next[i]
+		update[i].next[i] = n
+	}
+
+	if update[0] != m.head {
+		n.prev = update[0]
+	}
+
+	if n.next[0] != nil {
+		n.next[0].prev = n
+	} else {
+		m.tail = n
+	}
+
+	m.len++
+
+	return nil
+}
+
+// Del deletes the object.
+func (m *OrderedMapOf[K, V]) Del(key K) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var update [skipMaxLevel]*skipNode[K, V]
+
+	n := m.ceiling(key, &update)
+	if n == nil || m.less(key, n.key) {
+		return
+	}
+
+	for i := range n.next {
+		update[i].next[i] = n.next[i]
+	}
+
+	if n.next[0] != nil {
+		n.next[0].prev = n.prev
+	} else {
+		m.tail = n.prev
+	}
+
+	for m.level > 1 && m.head.next[m.level-1] == nil {
+		m.level--
+	}
+
+	m.len--
+}
+
+// Drain returns all elements as slice sorted by key and removes keys from the storage.
+func (m *OrderedMapOf[K, V]) Drain() []V {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data := make([]V, 0, m.len)
+
+	for n := m.head.next[0]; n != nil; n = n.next[0] {
+		data = append(data, n.value)
+	}
+
+	for i := range m.head.next {
+		m.head.next[i] = nil
+	}
+
+	m.tail = nil
+	m.level = 1
+	m.len = 0
+
+	return data
+}
+
+// Len returns count of elements in the storage.
+func (m *OrderedMapOf[K, V]) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return m.len
+}
+
+// Keys returns all the keys as a sorted slice.
+func (m *OrderedMapOf[K, V]) Keys() []K {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	keys := make([]K, 0, m.len)
+
+	for n := m.head.next[0]; n != nil; n = n.next[0] {
+		keys = append(keys, n.key)
+	}
+
+	return keys
+}
+
+// Min returns the smallest key and its value. It returns false if the map is empty.
+func (m *OrderedMapOf[K, V]) Min() (K, V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return entryOf(m.head.next[0])
+}
+
+// Max returns the largest key and its value. It returns false if the map is empty.
+func (m *OrderedMapOf[K, V]) Max() (K, V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return entryOf(m.tail)
+}
+
+// Floor returns the largest key less than or equal to the given one and its value.
+// It returns false if there is no such key.
+func (m *OrderedMapOf[K, V]) Floor(key K) (K, V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return entryOf(m.floor(key))
+}
+
+// Ceiling returns the smallest key greater than or equal to the given one and its value.
+// It returns false if there is no such key.
+func (m *OrderedMapOf[K, V]) Ceiling(key K) (K, V, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	return entryOf(m.ceiling(key, nil))
+}
+
+// Range calls fn for keys in [from, to) in increasing order until fn returns false.
+func (m *OrderedMapOf[K, V]) Range(from, to K, fn func(key K, value V) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for n := m.ceiling(from, nil); n != nil && m.less(n.key, to); n = n.next[0] {
+		if !fn(n.key, n.value) {
+			return
+		}
+	}
+}
+
+// Ascend calls fn for all keys in increasing order until fn returns false.
+func (m *OrderedMapOf[K, V]) Ascend(fn func(key K, value V) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for n := m.head.next[0]; n != nil; n = n.next[0] {
+		if !fn(n.key, n.value) {
+			return
+		}
+	}
+}
+
+// AscendFrom calls fn for keys greater than or equal to from in increasing order until fn returns false.
+func (m *OrderedMapOf[K, V]) AscendFrom(from K, fn func(key K, value V) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for n := m.ceiling(from, nil); n != nil; n = n.next[0] {
+		if !fn(n.key, n.value) {
+			return
+		}
+	}
+}
+
+// Descend calls fn for all keys in decreasing order until fn returns false.
+func (m *OrderedMapOf[K, V]) Descend(fn func(key K, value V) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for n := m.tail; n != nil; n = n.prev {
+		if !fn(n.key, n.value) {
+			return
+		}
+	}
+}
+
+// DescendFrom calls fn for keys less than or equal to from in decreasing order until fn returns false.
+func (m *OrderedMapOf[K, V]) DescendFrom(from K, fn func(key K, value V) bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for n := m.floor(from); n != nil; n = n.prev {
+		if !fn(n.key, n.value) {
+			return
+		}
+	}
+}
+
+// init prepares an empty map.
+func (m *OrderedMapOf[K, V]) init(less func(a, b K) bool) {
+	m.head = &skipNode[K, V]{next: make([]*skipNode[K, V], skipMaxLevel)}
+	m.level = 1
+	m.less = less
+	m.rnd = rand.New(rand.NewSource(time.Now().UnixNano()))
+}
+
+// ceiling returns the first node with a key greater than or equal to the given one, or nil.
+// If update is not nil, it receives the last node before the key at each level.
+func (m *OrderedMapOf[K, V]) ceiling(key K, update *[skipMaxLevel]*skipNode[K, V]) *skipNode[K, V] {
+	x := m.head
+
+	for i := m.level - 1; i >= 0; i-- {
+		for x.next[i] != nil && m.less(x.next[i].key, key) {
+			x = x.next[i]
+		}
+
+		if update != nil {
+			update[i] = x
+		}
+	}
+
+	return x.next[0]
+}
+
+// floor returns the last node with a key less than or equal to the given one, or nil.
+func (m *OrderedMapOf[K, V]) floor(key K) *skipNode[K, V] {
+	n := m.ceiling(key, nil)
+
+	switch {
+	case n == nil:
+		return m.tail
+	case !m.less(key, n.key):
+		return n
+	default:
+		return n.prev
+	}
+}
+
+// randomLevel returns the level of a new node, each level is four times less likely than the one below.
+func (m *OrderedMapOf[K, V]) randomLevel() int {
+	lvl := 1
+	for lvl < skipMaxLevel && m.rnd.Uint32()&3 == 0 {
+		lvl++
+	}
+
+	return lvl
+}
+
+// entryOf returns the key and the value of the node, if it is not nil.
+func entryOf[K comparable, V any](n *skipNode[K, V]) (K, V, bool) {
+	if n == nil {
+		var (
+			key   K
+			value V
+		)
+
+		return key, value, false
+	}
+
+	return n.key, n.value, true
+}
+
+// OrderedMap provides an ordered storage with string keys.
+type OrderedMap struct {
+	OrderedMapOf[string, interface{}]
+}
+
+// NewOrderedMap returns a ready to use instance of OrderedMap.
+func NewOrderedMap() *OrderedMap {
+	m := &OrderedMap{}
+	m.init(lessString)
+
+	return m
+}
+
+// Prefix calls fn for keys with the prefix in increasing order until fn returns false.
+func (m *OrderedMap) Prefix(prefix string, fn func(key string, value interface{}) bool) {
+	m.AscendFrom(prefix, func(key string, value interface{}) bool {
+		return strings.HasPrefix(key, prefix) && fn(key, value)
+	})
+}
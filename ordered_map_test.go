@@ -0,0 +1,251 @@
+package xtypes
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+)
+
+func TestNewOrderedMap(t *testing.T) {
+	m := NewOrderedMap()
+	if m == nil {
+		t.Fatalf("failed to create map")
+	}
+}
+
+func TestOrderedMapOf_Random(t *testing.T) {
+	m := NewOrderedMapOf[int, int](func(a, b int) bool { return a < b })
+	reference := make(map[int]int)
+
+	rnd := rand.New(rand.NewSource(1))
+
+	for i := 0; i < 10000; i++ {
+		k := rnd.Intn(500)
+
+		if rnd.Intn(3) == 0 {
+			m.Del(k)
+			delete(reference, k)
+		} else {
+			m.Set(k, i)
+			reference[k] = i
+		}
+	}
+
+	expected := make([]int, 0, len(reference))
+	for k := range reference {
+		expected = append(expected, k)
+	}
+
+	sort.Ints(expected)
+
+	if actual := m.Keys(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	if l := m.Len(); l != len(reference) {
+		t.Fatalf("expected %d, got %d", len(reference), l)
+	}
+
+	for k, v := range reference {
+		if actual, ok := m.Get(k); !ok || actual != v {
+			t.Fatalf("expected %d, got %d", v, actual)
+		}
+	}
+
+	// Reverse iteration visits the same keys backwards.
+	var reversed []int
+
+	m.Descend(func(k, v int) bool {
+		reversed = append(reversed, k)
+		return true
+	})
+
+	for i, k := range reversed {
+		if expected[len(expected)-1-i] != k {
+			t.Fatalf("expected %d, got %d", expected[len(expected)-1-i], k)
+		}
+	}
+
+	values := m.Drain()
+	if len(values) != len(expected) || values[0] != reference[expected[0]] {
+		t.Fatalf("expected %d values starting with %d, got %v", len(expected), reference[expected[0]], values)
+	}
+
+	if _, _, ok := m.Min(); ok || m.Len() != 0 {
+		t.Fatal("expected empty map")
+	}
+}
+
+func TestOrderedMapOf_Bounds(t *testing.T) {
+	m := NewOrderedMapOf[int, string](func(a, b int) bool { return a < b })
+
+	if _, _, ok := m.Max(); ok {
+		t.Fatal("expected empty map")
+	}
+
+	for _, k := range []int{50, 10, 40, 20, 30} {
+		m.Set(k, strconv.Itoa(k))
+	}
+
+	if k, v, ok := m.Min(); !ok || k != 10 || v != "10" {
+		t.Fatalf("expected %d, got %d", 10, k)
+	}
+
+	if k, _, _ := m.Max(); k != 50 {
+		t.Fatalf("expected %d, got %d", 50, k)
+	}
+
+	for _, tc := range []struct {
+		key            int
+		floor, ceiling int
+		hasFloor       bool
+		hasCeiling     bool
+	}{
+		{5, 0, 10, false, true},
+		{10, 10, 10, true, true},
+		{25, 20, 30, true, true},
+		{50, 50, 50, true, true},
+		{55, 50, 0, true, false},
+	} {
+		if k, _, ok := m.Floor(tc.key); ok != tc.hasFloor || k != tc.floor {
+			t.Fatalf("floor of %d: expected %d, got %d", tc.key, tc.floor, k)
+		}
+
+		if k, _, ok := m.Ceiling(tc.key); ok != tc.hasCeiling || k != tc.ceiling {
+			t.Fatalf("ceiling of %d: expected %d, got %d", tc.key, tc.ceiling, k)
+		}
+	}
+
+	m.Del(50)
+
+	if k, _, _ := m.Max(); k != 40 {
+		t.Fatalf("expected %d, got %d", 40, k)
+	}
+}
+
+func TestOrderedMapOf_Range(t *testing.T) {
+	m := NewOrderedMapOf[int, int](func(a, b int) bool { return a < b })
+
+	for i := 0; i < 100; i += 10 {
+		m.Set(i, i)
+	}
+
+	collect := func(iterate func(fn func(k, v int) bool), limit int) []int {
+		var keys []int
+
+		iterate(func(k, v int) bool {
+			keys = append(keys, k)
+			return len(keys) < limit
+		})
+
+		return keys
+	}
+
+	for _, tc := range []struct {
+		name     string
+		iterate  func(fn func(k, v int) bool)
+		limit    int
+		expected []int
+	}{
+		{"range", func(fn func(k, v int) bool) { m.Range(15, 50, fn) }, 10, []int{20, 30, 40}},
+		{"range empty", func(fn func(k, v int) bool) { m.Range(41, 50, fn) }, 10, nil},
+		{"range stop", func(fn func(k, v int) bool) { m.Range(0, 100, fn) }, 2, []int{0, 10}},
+		{"ascend stop", func(fn func(k, v int) bool) { m.Ascend(fn) }, 3, []int{0, 10, 20}},
+		{"ascend from", func(fn func(k, v int) bool) { m.AscendFrom(75, fn) }, 10, []int{80, 90}},
+		{"descend stop", func(fn func(k, v int) bool) { m.Descend(fn) }, 3, []int{90, 80, 70}},
+		{"descend from", func(fn func(k, v int) bool) { m.DescendFrom(25, fn) }, 10, []int{20, 10, 0}},
+	} {
+		if actual := collect(tc.iterate, tc.limit); !reflect.DeepEqual(tc.expected, actual) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.expected, actual)
+		}
+	}
+}
+
+func TestOrderedMap_Prefix(t *testing.T) {
+	m := NewOrderedMap()
+
+	for _, k := range []string{"app", "app.b", "app.a", "apple", "b", "ap"} {
+		m.Set(k, k)
+	}
+
+	var keys []string
+
+	m.Prefix("app.", func(key string, value interface{}) bool {
+		keys = append(keys, key)
+		return true
+	})
+
+	expected := []string{"app.a", "app.b"}
+	if !reflect.DeepEqual(expected, keys) {
+		t.Fatalf("expected %v, got %v", expected, keys)
+	}
+}
+
+func TestOrderedMapOf_Concurrent(t *testing.T) {
+	m := NewOrderedMapOf[int, int](func(a, b int) bool { return a < b })
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for j := 0; j < 500; j++ {
+				if w%2 == 0 {
+					m.Set(w*1000+j, j)
+					continue
+				}
+
+				prev := -1
+
+				m.Ascend(func(k, v int) bool {
+					if k <= prev {
+						t.Errorf("expected keys in order, got %d after %d", k, prev)
+					}
+
+					prev = k
+
+					return k < 100
+				})
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if l := m.Len(); l != 2000 {
+		t.Fatalf("expected %d, got %d", 2000, l)
+	}
+}
+
+// Benchmarks.
+
+func BenchmarkOrderedMap_Keys(b *testing.B) {
+	m := NewOrderedMap()
+
+	benchmarkKeys(b, m.Set, m.Keys)
+}
+
+func BenchmarkSafeMap_Keys(b *testing.B) {
+	sm := NewSafeMap()
+
+	benchmarkKeys(b, sm.Set, sm.Keys)
+}
+
+// benchmarkKeys measures sorted key listing of a map filled through set.
+func benchmarkKeys(b *testing.B, set func(key string, value interface{}) error, keys func() []string) {
+	for i := 0; i < benchMapKeys; i++ {
+		set(strconv.Itoa(i), i)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		keys()
+	}
+}
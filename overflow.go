@@ -0,0 +1,39 @@
+package xtypes
+
+// OverflowPolicy defines what a bounded queue does when an item is added while the queue is full.
+//
+// It also defines what a map watch does with an event when its subscriber lags behind, see MapWatch.
+type OverflowPolicy int
+
+const (
+	// OverflowReject rejects the new item with ErrFull.
+	OverflowReject OverflowPolicy = iota
+
+	// OverflowBlock blocks the producer until space frees up.
+	OverflowBlock
+
+	// OverflowDropOldest removes the item which has been in the queue for the longest time to make room.
+	OverflowDropOldest
+
+	// OverflowEvictLowest removes the item which would be popped last to make room.
+	// If it is the new item, the new item is dropped and ErrDropped is returned.
+	//
+	// It is supported by PriorityQueueOf only, QueueOf treats it as OverflowReject.
+	OverflowEvictLowest
+)
+
+// String returns the name of the policy.
+func (p OverflowPolicy) String() string {
+	switch p {
+	case OverflowReject:
+		return "reject"
+	case OverflowBlock:
+		return "block"
+	case OverflowDropOldest:
+		return "drop oldest"
+	case OverflowEvictLowest:
+		return "evict lowest"
+	default:
+		return "unknown"
+	}
+}
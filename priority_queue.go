@@ -2,6 +2,7 @@ package xtypes
 
 import (
 	"container/heap"
+	"context"
 	"sync"
 )
 
@@ -12,54 +13,75 @@ type PQItem interface {
 	SetIndex(idx int)
 }
 
-// PriorityQueue is a priority queue implemented using heap.
+// PriorityQueueOf is a generic priority queue implemented using heap.
 //
-// This is clean and simple thread safe implementation with no magic.
-// PriorityQueue MUST be created using constructor.
-type PriorityQueue struct {
-	mu    sync.Mutex // Protects items.
-	items PQItems
+// The order of items is defined by the less function passed to the constructor.
+// An item for which less reports true comes out first, so a min-heap, a max-heap or any multi-key ordering can be expressed.
+// Items which are equal according to less come out in arbitrary order unless the queue is stable, see SetStable.
+// A bounded queue holds at most limit items and applies its OverflowPolicy when it is full.
+// PriorityQueueOf MUST be created using constructor.
+type PriorityQueueOf[T any] struct {
+	mu        sync.Mutex // Protects items.
+	items     pqHeap[T]
+	consumers waiters // Consumers blocked in PopWait and GetWait.
+	producers waiters // Producers blocked on a full queue.
+	closed    bool
+	limit     int // Zero means unbounded.
+	policy    OverflowPolicy
+	dropped   uint64
 }
 
-// NewPriorityQueue creates and inits a new PriorityQueue.
-func NewPriorityQueue(hint int) *PriorityQueue {
-	pq := &PriorityQueue{
-		items: make([]PQItem, 0, hint),
-	}
+// NewPriorityQueueOf creates and inits a new PriorityQueueOf ordered by less.
+func NewPriorityQueueOf[T any](hint int, less func(a, b T) bool) *PriorityQueueOf[T] {
+	pq := &PriorityQueueOf[T]{}
+	pq.init(hint, 0, OverflowReject, less)
 
-	heap.Init(&pq.items)
+	return pq
+}
+
+// NewBoundedPriorityQueueOf creates and inits a new PriorityQueueOf ordered by less which holds at most limit items.
+//
+// A limit less than 1 means the queue is unbounded.
+func NewBoundedPriorityQueueOf[T any](hint, limit int, policy OverflowPolicy, less func(a, b T) bool) *PriorityQueueOf[T] {
+	pq := &PriorityQueueOf[T]{}
+	pq.init(hint, limit, policy, less)
 
 	return pq
 }
 
 // Get returns up to requested n of items.
-func (pq *PriorityQueue) Get(n int) ([]PQItem, error) {
+func (pq *PriorityQueueOf[T]) Get(n int) ([]T, error) {
 	if n < 1 {
-		return []PQItem{}, nil
+		return []T{}, nil
 	}
 
 	pq.mu.Lock()
 	defer pq.mu.Unlock()
 
-	if pq.items == nil {
+	if pq.items.items == nil {
 		return nil, ErrInvalidQueue
 	}
 
-	result := make([]PQItem, 0, n)
-
-	for i := 0; i < n; i++ {
-		if len(pq.items) == 0 {
-			break
-		}
-
-		result = append(result, heap.Pop(&pq.items).(PQItem))
+	if pq.closed && pq.items.Len() == 0 {
+		return nil, ErrClosed
 	}
 
-	return result, nil
+	return pq.get(n), nil
 }
 
 // Put adds items to queue.
-func (pq *PriorityQueue) Put(items ...PQItem) error {
+//
+// If the queue is bounded, the overflow policy is applied to each item.
+// With OverflowReject no items are added unless all of them fit.
+// With OverflowEvictLowest the remaining items are still added when one is dropped, ErrDropped is returned then.
+// With OverflowBlock Put waits for space, see PutWait.
+func (pq *PriorityQueueOf[T]) Put(items ...T) error {
+	return pq.PutWait(context.Background(), items...)
+}
+
+// PutWait is Put which gives up waiting for space once ctx is done and returns ctx.Err().
+// Items added before the cancellation stay in the queue.
+func (pq *PriorityQueueOf[T]) PutWait(ctx context.Context, items ...T) error {
 	if len(items) == 0 {
 		return nil
 	}
@@ -67,72 +89,517 @@ func (pq *PriorityQueue) Put(items ...PQItem) error {
 	pq.mu.Lock()
 	defer pq.mu.Unlock()
 
-	if pq.items == nil {
+	if pq.items.items == nil {
 		return ErrInvalidQueue
 	}
 
+	if pq.closed {
+		return ErrClosed
+	}
+
+	if pq.rejects(len(items)) {
+		return ErrFull
+	}
+
+	var dropped error
+
 	for _, item := range items {
-		heap.Push(&pq.items, item)
+		switch err := pq.push(ctx, item); err {
+		case nil:
+		case ErrDropped:
+			dropped = err
+		default:
+			return err
+		}
 	}
 
-	return nil
+	return dropped
+}
+
+// Pop returns the first element from the queue. If the queue is empty - an error will be returned,
+// ErrClosed if the queue has been closed, ErrEmptyQueue otherwise.
+func (pq *PriorityQueueOf[T]) Pop() (T, error) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if pq.items.Len() == 0 {
+		var zero T
+
+		if pq.closed {
+			return zero, ErrClosed
+		}
+
+		return zero, ErrEmptyQueue
+	}
+
+	return pq.pop(), nil
+}
+
+// GetWait returns up to requested n of items.
+// If the queue is empty it blocks until an item is added, the queue is closed or ctx is done.
+// ErrClosed is returned once the queue is closed and has no items left, ctx.Err() is returned on cancellation.
+func (pq *PriorityQueueOf[T]) GetWait(ctx context.Context, n int) ([]T, error) {
+	if n < 1 {
+		return []T{}, nil
+	}
+
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if pq.items.items == nil {
+		return nil, ErrInvalidQueue
+	}
+
+	if err := pq.consumers.wait(ctx, &pq.mu, pq.ready); err != nil {
+		return nil, err
+	}
+
+	if pq.items.Len() == 0 {
+		return nil, ErrClosed
+	}
+
+	return pq.get(n), nil
 }
 
-// Pop returns the first element from the queue. If the queue is empty - an error will be returned.
-func (pq *PriorityQueue) Pop() (PQItem, error) {
+// PopWait returns the first element from the queue.
+// If the queue is empty it blocks until an item is added, the queue is closed or ctx is done.
+// ErrClosed is returned once the queue is closed and has no items left, ctx.Err() is returned on cancellation.
+func (pq *PriorityQueueOf[T]) PopWait(ctx context.Context) (T, error) {
+	var zero T
+
 	pq.mu.Lock()
 	defer pq.mu.Unlock()
 
-	if len(pq.items) == 0 {
-		return nil, ErrEmptyQueue
+	if pq.items.items == nil {
+		return zero, ErrInvalidQueue
 	}
 
-	return heap.Pop(&pq.items).(PQItem), nil
+	if err := pq.consumers.wait(ctx, &pq.mu, pq.ready); err != nil {
+		return zero, err
+	}
+
+	if pq.items.Len() == 0 {
+		return zero, ErrClosed
+	}
+
+	return pq.pop(), nil
 }
 
 // Push adds an item to the queue. If the underlying storage is nil - an error will be returned.
-func (pq *PriorityQueue) Push(item PQItem) error {
+//
+// If the queue is bounded and full, the overflow policy is applied.
+// With OverflowEvictLowest ErrDropped is returned if the item would be popped last and is not added.
+// With OverflowBlock Push waits for space, see PushWait.
+func (pq *PriorityQueueOf[T]) Push(item T) error {
+	return pq.PushWait(context.Background(), item)
+}
+
+// PushWait is Push which gives up waiting for space once ctx is done and returns ctx.Err().
+func (pq *PriorityQueueOf[T]) PushWait(ctx context.Context, item T) error {
 	pq.mu.Lock()
 	defer pq.mu.Unlock()
 
-	if pq.items == nil {
+	if pq.items.items == nil {
 		return ErrInvalidQueue
 	}
 
-	heap.Push(&pq.items, item)
+	if pq.closed {
+		return ErrClosed
+	}
 
-	return nil
+	return pq.push(ctx, item)
 }
 
 // Peek returns the first element without modifying the queue.
-func (pq *PriorityQueue) Peek() PQItem {
+// If the queue is empty - the zero value of T is returned.
+func (pq *PriorityQueueOf[T]) Peek() T {
 	pq.mu.Lock()
 	defer pq.mu.Unlock()
 
-	if len(pq.items) == 0 {
-		return nil
+	if pq.items.Len() == 0 {
+		var zero T
+
+		return zero
 	}
 
-	return pq.items[0]
+	return pq.items.items[0].item
 }
 
 // Len returns the len of the queue.
-func (pq *PriorityQueue) Len() int {
+func (pq *PriorityQueueOf[T]) Len() int {
 	pq.mu.Lock()
 	defer pq.mu.Unlock()
 
-	return len(pq.items)
+	return pq.items.Len()
 }
 
 // Empty returns true if the queue is empty.
-func (pq *PriorityQueue) Empty() bool {
+func (pq *PriorityQueueOf[T]) Empty() bool {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	return pq.items.Len() == 0
+}
+
+// SetStable switches the stable mode of the queue.
+//
+// In the stable mode items which are equal according to less come out in the order they have been added.
+// The insertion order is tracked by the queue, so items do not need to carry a counter.
+// Items already in the queue are reordered accordingly.
+func (pq *PriorityQueueOf[T]) SetStable(stable bool) {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if pq.items.stable == stable {
+		return
+	}
+
+	pq.items.stable = stable
+
+	heap.Init(&pq.items)
+}
+
+// Policy returns the overflow policy of the queue.
+func (pq *PriorityQueueOf[T]) Policy() OverflowPolicy {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	return pq.policy
+}
+
+// Dropped returns the number of items removed by the overflow policy to make room,
+// including new items dropped by OverflowEvictLowest. Items rejected with ErrFull are not counted.
+func (pq *PriorityQueueOf[T]) Dropped() uint64 {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	return pq.dropped
+}
+
+// Close closes the queue.
+//
+// Push and Put return ErrClosed afterwards, blocked consumers and producers are woken up.
+// Items left in the queue can still be consumed or taken with Drain.
+// Closing a closed queue returns ErrClosed.
+func (pq *PriorityQueueOf[T]) Close() error {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	if pq.items.items == nil {
+		return ErrInvalidQueue
+	}
+
+	if pq.closed {
+		return ErrClosed
+	}
+
+	pq.closed = true
+	pq.consumers.broadcast()
+	pq.producers.broadcast()
+
+	return nil
+}
+
+// Drain removes and returns all items left in the queue in the order they would have been popped.
+func (pq *PriorityQueueOf[T]) Drain() []T {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	return pq.get(pq.items.Len())
+}
+
+// init prepares the storage, a limit less than 1 means the queue is unbounded.
+func (pq *PriorityQueueOf[T]) init(hint, limit int, policy OverflowPolicy, less func(a, b T) bool) {
+	if limit > 0 {
+		hint = min(hint, limit)
+	}
+
+	pq.items = newPQHeap(hint, less)
+	pq.limit = max(limit, 0)
+	pq.policy = policy
+
+	heap.Init(&pq.items)
+}
+
+// push adds item to the queue applying the overflow policy.
+func (pq *PriorityQueueOf[T]) push(ctx context.Context, item T) error {
+	if pq.full() {
+		switch pq.policy {
+		case OverflowBlock:
+			if err := pq.producers.wait(ctx, &pq.mu, pq.room); err != nil {
+				return err
+			}
+
+			if pq.closed {
+				return ErrClosed
+			}
+		case OverflowDropOldest:
+			heap.Remove(&pq.items, pq.items.oldest())
+			pq.dropped++
+		case OverflowEvictLowest:
+			pq.dropped++
+
+			i := pq.items.lowest()
+			if !pq.items.less(item, pq.items.items[i].item) {
+				return ErrDropped
+			}
+
+			heap.Remove(&pq.items, i)
+		default:
+			return ErrFull
+		}
+	}
+
+	heap.Push(&pq.items, item)
+	pq.consumers.signal(1)
+
+	return nil
+}
+
+// pop removes the first item and wakes a blocked producer.
+func (pq *PriorityQueueOf[T]) pop() T {
+	// A nil item of an interface type comes back as a nil interface{}.
+	item, _ := heap.Pop(&pq.items).(T)
+	pq.producers.signal(1)
+
+	return item
+}
+
+// get removes up to n items.
+func (pq *PriorityQueueOf[T]) get(n int) []T {
+	result := make([]T, 0, min(n, pq.items.Len()))
+
+	for i := 0; i < n && pq.items.Len() > 0; i++ {
+		result = append(result, pq.pop())
+	}
+
+	return result
+}
+
+// full reports whether the queue has reached its limit.
+func (pq *PriorityQueueOf[T]) full() bool {
+	return pq.limit > 0 && pq.items.Len() >= pq.limit
+}
+
+// rejects reports whether adding n items is rejected as a whole.
+func (pq *PriorityQueueOf[T]) rejects(n int) bool {
+	switch pq.policy {
+	case OverflowBlock, OverflowDropOldest, OverflowEvictLowest:
+		return false
+	default:
+		return pq.limit > 0 && pq.items.Len()+n > pq.limit
+	}
+}
+
+// ready reports whether a waiting consumer can proceed.
+func (pq *PriorityQueueOf[T]) ready() bool {
+	return pq.items.Len() > 0 || pq.closed
+}
+
+// room reports whether a waiting producer can proceed.
+func (pq *PriorityQueueOf[T]) room() bool {
+	return !pq.full() || pq.closed
+}
+
+// PriorityQueue is a priority queue of PQItem ordered by Priority, the lowest priority comes first.
+//
+// It is a thin wrapper around PriorityQueueOf kept for compatibility.
+// Indices of items are maintained via SetIndex.
+// PriorityQueue MUST be created using constructor.
+type PriorityQueue struct {
+	PriorityQueueOf[PQItem]
+}
+
+// NewPriorityQueue creates and inits a new PriorityQueue.
+func NewPriorityQueue(hint int) *PriorityQueue {
+	return NewBoundedPriorityQueue(hint, 0, OverflowReject)
+}
+
+// NewBoundedPriorityQueue creates and inits a new PriorityQueue which holds at most limit items.
+//
+// A limit less than 1 means the queue is unbounded.
+func NewBoundedPriorityQueue(hint, limit int, policy OverflowPolicy) *PriorityQueue {
+	pq := &PriorityQueue{}
+
+	pq.init(hint, limit, policy, lessPQItem)
+	pq.items.setIndex = setPQItemIndex
+
+	return pq
+}
+
+// Update restores the order of the queue after the priority of item has changed.
+//
+// The item is located by its index, ErrNotInQueue is returned if it does not belong to the queue.
+// Items MUST be comparable, e.g. pointers.
+func (pq *PriorityQueue) Update(item PQItem) error {
+	pq.mu.Lock()
+	defer pq.mu.Unlock()
+
+	idx, err := pq.indexOf(item)
+	if err != nil {
+		return err
+	}
+
+	heap.Fix(&pq.items, idx)
+
+	return nil
+}
+
+// Remove removes item from the queue.
+//
+// The item is located by its index, ErrNotInQueue is returned if it does not belong to the queue.
+// Items MUST be comparable, e.g. pointers.
+func (pq *PriorityQueue) Remove(item PQItem) error {
 	pq.mu.Lock()
 	defer pq.mu.Unlock()
 
-	return len(pq.items) == 0
+	idx, err := pq.indexOf(item)
+	if err != nil {
+		return err
+	}
+
+	heap.Remove(&pq.items, idx)
+	pq.producers.signal(1)
+
+	return nil
+}
+
+// indexOf returns the position of item in the heap after making sure it is the same item.
+func (pq *PriorityQueue) indexOf(item PQItem) (int, error) {
+	if pq.items.items == nil {
+		return 0, ErrInvalidQueue
+	}
+
+	if item == nil {
+		return 0, ErrNotInQueue
+	}
+
+	idx := item.Index()
+	if idx < 0 || idx >= len(pq.items.items) || pq.items.items[idx].item != item {
+		return 0, ErrNotInQueue
+	}
+
+	return idx, nil
+}
+
+// lessPQItem orders PQItem by priority.
+func lessPQItem(a, b PQItem) bool {
+	return a.Priority() < b.Priority()
+}
+
+// setPQItemIndex keeps the index of PQItem up to date.
+func setPQItemIndex(item PQItem, idx int) {
+	item.SetIndex(idx)
+}
+
+// pqEntry is an item stored in pqHeap.
+type pqEntry[T any] struct {
+	item T
+	seq  uint64 // Insertion order.
+}
+
+// pqHeap implements heap.Interface for items of any type.
+type pqHeap[T any] struct {
+	items    []pqEntry[T]
+	seq      uint64 // Sequence number of the next item.
+	less     func(a, b T) bool
+	setIndex func(item T, idx int) // Optional, called whenever the position of an item changes.
+	stable   bool                  // Breaks ties by insertion order.
+}
+
+// newPQHeap returns a heap with storage preallocated for hint items.
+func newPQHeap[T any](hint int, less func(a, b T) bool) pqHeap[T] {
+	return pqHeap[T]{
+		items: make([]pqEntry[T], 0, max(hint, 0)),
+		less:  less,
+	}
+}
+
+// Pop implements heap.Interface. Returns the last item.
+func (h *pqHeap[T]) Pop() interface{} {
+	n := len(h.items)
+
+	item := h.items[n-1].item
+	if h.setIndex != nil {
+		h.setIndex(item, -1)
+	}
+
+	// Prevent leaks.
+	h.items[n-1], h.items = pqEntry[T]{}, h.items[0:n-1]
+
+	return item
+}
+
+// Push implements heap.Interface. Inserts the x to the heap.
+func (h *pqHeap[T]) Push(x interface{}) {
+	item, _ := x.(T) // A nil item of an interface type is a nil interface{}.
+	if h.setIndex != nil {
+		h.setIndex(item, len(h.items))
+	}
+
+	h.items = append(h.items, pqEntry[T]{item: item, seq: h.seq})
+	h.seq++
+}
+
+// Len implements heap.Interface. Returns the len of underlying storage.
+func (h *pqHeap[T]) Len() int {
+	return len(h.items)
+}
+
+// Less implements heap.Interface. Returns true if inequality is met.
+// In the stable mode equal items are ordered by insertion.
+func (h *pqHeap[T]) Less(i, j int) bool {
+	a, b := &h.items[i], &h.items[j]
+
+	if h.less(a.item, b.item) {
+		return true
+	}
+
+	return h.stable && a.seq < b.seq && !h.less(b.item, a.item)
+}
+
+// Swap implements heap.Interface. Swaps two elements.
+func (h *pqHeap[T]) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+
+	if h.setIndex != nil {
+		h.setIndex(h.items[i].item, i)
+		h.setIndex(h.items[j].item, j)
+	}
+}
+
+// oldest returns the index of the item which has been in the heap for the longest time.
+// The heap MUST NOT be empty.
+func (h *pqHeap[T]) oldest() int {
+	idx := 0
+
+	for i := 1; i < len(h.items); i++ {
+		if h.items[i].seq < h.items[idx].seq {
+			idx = i
+		}
+	}
+
+	return idx
+}
+
+// lowest returns the index of the item which would be popped last.
+// The heap MUST NOT be empty.
+func (h *pqHeap[T]) lowest() int {
+	// The item is one of the leaves.
+	idx := len(h.items) / 2
+
+	for i := idx + 1; i < len(h.items); i++ {
+		if h.Less(idx, i) {
+			idx = i
+		}
+	}
+
+	return idx
 }
 
 // PQItems represents the queue items.
+//
+// It implements heap.Interface and is kept for compatibility, PriorityQueue does not use it.
 type PQItems []PQItem
 
 // Pop implements heap.Interface. Returns the first item.
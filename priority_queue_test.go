@@ -1,8 +1,11 @@
 package xtypes
 
 import (
+	"context"
+	"fmt"
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestNewPriorityQueue(t *testing.T) {
@@ -214,3 +217,525 @@ func TestPriorityQueue_Empty(t *testing.T) {
 		t.Fatalf("expected empty queue, got non-empty")
 	}
 }
+
+func TestPriorityQueue_Index(t *testing.T) {
+	q := NewPriorityQueue(4)
+
+	items := []*mockItem{
+		{value: "put 1", priority: 30},
+		{value: "put 2", priority: 10},
+		{value: "put 3", priority: 20},
+	}
+
+	for _, item := range items {
+		q.Push(item)
+	}
+
+	for i, e := range q.items.items {
+		item := e.item
+		if item.Index() != i {
+			t.Fatalf("expected %d, got %d", i, item.Index())
+		}
+	}
+
+	v, err := q.Pop()
+	if err != nil {
+		t.Fatalf("failed to pop queue: %v", err)
+	}
+
+	if v.Index() != -1 {
+		t.Fatalf("expected %d, got %d", -1, v.Index())
+	}
+}
+
+func TestPriorityQueueOf_MinHeap(t *testing.T) {
+	q := NewPriorityQueueOf(4, func(a, b int) bool { return a < b })
+
+	if err := q.Put(5, 1, 4, 2, 3); err != nil {
+		t.Fatalf("failed to put to queue: %v", err)
+	}
+
+	expected := []int{1, 2, 3, 4, 5}
+
+	actual, err := q.Get(q.Len())
+	if err != nil {
+		t.Fatalf("failed to get from queue: %v", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestPriorityQueueOf_MaxHeap(t *testing.T) {
+	q := NewPriorityQueueOf(4, func(a, b float64) bool { return a > b })
+
+	q.Put(0.5, 2.5, 1.5)
+
+	if v := q.Peek(); v != 2.5 {
+		t.Fatalf("expected %v, got %v", 2.5, v)
+	}
+
+	v, err := q.Pop()
+	if err != nil {
+		t.Fatalf("failed to pop queue: %v", err)
+	}
+
+	if v != 2.5 {
+		t.Fatalf("expected %v, got %v", 2.5, v)
+	}
+}
+
+func TestPriorityQueueOf_MultiKey(t *testing.T) {
+	type task struct {
+		level int
+		name  string
+	}
+
+	q := NewPriorityQueueOf(4, func(a, b task) bool {
+		if a.level != b.level {
+			return a.level > b.level
+		}
+
+		return a.name < b.name
+	})
+
+	q.Put(task{1, "b"}, task{2, "z"}, task{1, "a"}, task{2, "c"})
+
+	expected := []task{{2, "c"}, {2, "z"}, {1, "a"}, {1, "b"}}
+
+	actual, err := q.Get(4)
+	if err != nil {
+		t.Fatalf("failed to get from queue: %v", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestPriorityQueueOf_NilItem(t *testing.T) {
+	// Nil errors come first.
+	q := NewPriorityQueueOf[error](0, func(a, b error) bool { return a == nil && b != nil })
+
+	errSome := fmt.Errorf("some")
+
+	if err := q.Put(errSome, nil); err != nil {
+		t.Fatalf("failed to put to queue: %v", err)
+	}
+
+	if err := q.Push(nil); err != nil {
+		t.Fatalf("failed to push to queue: %v", err)
+	}
+
+	expected := []error{nil, nil, errSome}
+	for _, e := range expected {
+		if v, err := q.Pop(); err != nil || v != e {
+			t.Fatalf("expected %v, got %v, %v", e, v, err)
+		}
+	}
+}
+
+func TestPriorityQueueOf_PopEmpty(t *testing.T) {
+	q := NewPriorityQueueOf(1, func(a, b string) bool { return a < b })
+
+	v, err := q.Pop()
+	if err != ErrEmptyQueue {
+		t.Fatalf("expected %v, got %v ", ErrEmptyQueue, err)
+	}
+
+	if v != "" {
+		t.Fatalf("expected zero value, got %q", v)
+	}
+}
+
+func TestPriorityQueueOf_Invalid(t *testing.T) {
+	q := &PriorityQueueOf[int]{}
+
+	expected := ErrInvalidQueue
+
+	if err := q.Push(1); err != expected {
+		t.Fatalf("expected %v, got %v ", expected, err)
+	}
+
+	if _, err := q.Get(1); err != expected {
+		t.Fatalf("expected %v, got %v ", expected, err)
+	}
+}
+
+func TestPriorityQueue_PopWait(t *testing.T) {
+	q := NewPriorityQueue(1)
+
+	expected := &mockItem{value: "put 1", priority: 10}
+
+	result := make(chan PQItem)
+
+	go func() {
+		v, err := q.PopWait(context.Background())
+		if err != nil {
+			t.Errorf("failed to pop queue: %v", err)
+		}
+
+		result <- v
+	}()
+
+	q.Push(expected)
+
+	if v := <-result; !reflect.DeepEqual(expected, v) {
+		t.Fatalf("expected %v, got %v", *expected, v)
+	}
+}
+
+func TestPriorityQueueOf_PopWaitCancel(t *testing.T) {
+	q := NewPriorityQueueOf(1, func(a, b int) bool { return a < b })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := q.PopWait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if _, err := q.GetWait(ctx, 2); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+}
+
+func TestPriorityQueueOf_GetWait(t *testing.T) {
+	q := NewPriorityQueueOf(1, func(a, b int) bool { return a < b })
+
+	q.Put(3, 1, 2)
+
+	v, err := q.GetWait(context.Background(), 2)
+	if err != nil {
+		t.Fatalf("failed to get from queue: %v", err)
+	}
+
+	if expected := []int{1, 2}; !reflect.DeepEqual(expected, v) {
+		t.Fatalf("expected %v, got %v", expected, v)
+	}
+}
+
+func TestPriorityQueueOf_Close(t *testing.T) {
+	q := NewPriorityQueueOf(1, func(a, b int) bool { return a < b })
+
+	q.Put(3, 1, 2)
+
+	done := make(chan error)
+
+	go func() {
+		// Drains the queue and waits for more.
+		for {
+			if _, err := q.PopWait(context.Background()); err != nil {
+				done <- err
+				return
+			}
+		}
+	}()
+
+	q.Close()
+
+	if err := <-done; err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+
+	if err := q.Push(4); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+
+	if _, err := q.Pop(); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+}
+
+func TestPriorityQueue_Drain(t *testing.T) {
+	q := NewPriorityQueue(2)
+
+	v1 := &mockItem{value: "put 1", priority: 10}
+	v2 := &mockItem{value: "put 2", priority: 1}
+
+	q.Put(v1, v2)
+
+	if err := q.Close(); err != nil {
+		t.Fatalf("failed to close queue: %v", err)
+	}
+
+	expected := []PQItem{v2, v1}
+	if actual := q.Drain(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	if v1.Index() != -1 || v2.Index() != -1 {
+		t.Fatalf("expected drained items to have index %d", -1)
+	}
+}
+
+func TestPriorityQueueOf_BoundedReject(t *testing.T) {
+	q := NewBoundedPriorityQueueOf(0, 2, OverflowReject, func(a, b int) bool { return a < b })
+
+	if err := q.Put(3, 2, 1); err != ErrFull {
+		t.Fatalf("expected %v, got %v", ErrFull, err)
+	}
+
+	q.Put(3, 2)
+
+	if err := q.Push(1); err != ErrFull {
+		t.Fatalf("expected %v, got %v", ErrFull, err)
+	}
+}
+
+func TestPriorityQueueOf_BoundedEvictLowest(t *testing.T) {
+	q := NewBoundedPriorityQueueOf(0, 3, OverflowEvictLowest, func(a, b int) bool { return a < b })
+
+	q.Put(5, 3, 9)
+
+	// 1 evicts 9, 7 is the lowest itself and is dropped.
+	if err := q.Push(1); err != nil {
+		t.Fatalf("failed to push to queue: %v", err)
+	}
+
+	if err := q.Push(7); err != ErrDropped {
+		t.Fatalf("expected %v, got %v", ErrDropped, err)
+	}
+
+	expected := []int{1, 3, 5}
+	if actual := q.Drain(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	if d := q.Dropped(); d != 2 {
+		t.Fatalf("expected %d, got %d", 2, d)
+	}
+}
+
+func TestPriorityQueueOf_BoundedDropOldest(t *testing.T) {
+	q := NewBoundedPriorityQueueOf(0, 3, OverflowDropOldest, func(a, b int) bool { return a < b })
+
+	q.Put(5, 3, 9, 1)
+
+	expected := []int{1, 3, 9}
+	if actual := q.Drain(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	if d := q.Dropped(); d != 1 {
+		t.Fatalf("expected %d, got %d", 1, d)
+	}
+}
+
+func TestPriorityQueueOf_BoundedBlock(t *testing.T) {
+	q := NewBoundedPriorityQueueOf(0, 1, OverflowBlock, func(a, b int) bool { return a < b })
+
+	q.Push(2)
+
+	done := make(chan error)
+
+	go func() {
+		done <- q.Push(1)
+	}()
+
+	if v, err := q.PopWait(context.Background()); err != nil || v != 2 {
+		t.Fatalf("expected %d, got %d: %v", 2, v, err)
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("failed to push to queue: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := q.PushWait(ctx, 3); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+}
+
+func TestPriorityQueue_BoundedEvictLowest(t *testing.T) {
+	q := NewBoundedPriorityQueue(2, 2, OverflowEvictLowest)
+
+	v1 := &mockItem{value: "put 1", priority: 10}
+	v2 := &mockItem{value: "put 2", priority: 1}
+	v3 := &mockItem{value: "put 3", priority: 5}
+
+	v4 := &mockItem{value: "put 4", priority: 20}
+
+	// The remaining items are added after v4 is dropped.
+	if err := q.Put(v1, v2, v4, v3); err != ErrDropped {
+		t.Fatalf("expected %v, got %v", ErrDropped, err)
+	}
+
+	if v1.Index() != -1 {
+		t.Fatalf("expected evicted item to have index %d, got %d", -1, v1.Index())
+	}
+
+	expected := []PQItem{v2, v3}
+	if actual := q.Drain(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestPriorityQueue_Update(t *testing.T) {
+	q := NewPriorityQueue(3)
+
+	v1 := &mockItem{value: "put 1", priority: 10}
+	v2 := &mockItem{value: "put 2", priority: 20}
+	v3 := &mockItem{value: "put 3", priority: 30}
+
+	q.Put(v1, v2, v3)
+
+	v3.priority = 1
+
+	if err := q.Update(v3); err != nil {
+		t.Fatalf("failed to update item: %v", err)
+	}
+
+	v1.priority = 40
+
+	if err := q.Update(v1); err != nil {
+		t.Fatalf("failed to update item: %v", err)
+	}
+
+	expected := []PQItem{v3, v2, v1}
+	if actual := q.Drain(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestPriorityQueue_Remove(t *testing.T) {
+	q := NewPriorityQueue(3)
+
+	v1 := &mockItem{value: "put 1", priority: 10}
+	v2 := &mockItem{value: "put 2", priority: 20}
+	v3 := &mockItem{value: "put 3", priority: 30}
+
+	q.Put(v1, v2, v3)
+
+	if err := q.Remove(v2); err != nil {
+		t.Fatalf("failed to remove item: %v", err)
+	}
+
+	if v2.Index() != -1 {
+		t.Fatalf("expected %d, got %d", -1, v2.Index())
+	}
+
+	if err := q.Remove(v2); err != ErrNotInQueue {
+		t.Fatalf("expected %v, got %v", ErrNotInQueue, err)
+	}
+
+	expected := []PQItem{v1, v3}
+	if actual := q.Drain(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestPriorityQueue_UpdateForeign(t *testing.T) {
+	q1 := NewPriorityQueue(1)
+	q2 := NewPriorityQueue(1)
+
+	v1 := &mockItem{value: "put 1", priority: 10}
+	v2 := &mockItem{value: "put 2", priority: 20}
+
+	q1.Push(v1)
+	q2.Push(v2)
+
+	// Both items have index 0, but v2 belongs to another queue.
+	if err := q1.Update(v2); err != ErrNotInQueue {
+		t.Fatalf("expected %v, got %v", ErrNotInQueue, err)
+	}
+
+	if err := q1.Remove(v2); err != ErrNotInQueue {
+		t.Fatalf("expected %v, got %v", ErrNotInQueue, err)
+	}
+
+	if err := q1.Update(nil); err != ErrNotInQueue {
+		t.Fatalf("expected %v, got %v", ErrNotInQueue, err)
+	}
+
+	if err := (&PriorityQueue{}).Remove(v1); err != ErrInvalidQueue {
+		t.Fatalf("expected %v, got %v", ErrInvalidQueue, err)
+	}
+
+	if l := q2.Len(); l != 1 {
+		t.Fatalf("expected %d, got %d", 1, l)
+	}
+}
+
+func TestPriorityQueue_Stable(t *testing.T) {
+	q := NewPriorityQueue(64)
+	q.SetStable(true)
+
+	const (
+		bands   = 4
+		perBand = 16
+	)
+
+	// Interleave bands so that the heap shuffles items of the same priority.
+	for i := 0; i < perBand; i++ {
+		for b := bands - 1; b >= 0; b-- {
+			q.Push(&mockItem{value: fmt.Sprintf("%d-%02d", b, i), priority: b})
+		}
+	}
+
+	items := q.Drain()
+	if len(items) != bands*perBand {
+		t.Fatalf("expected %d items, got %d items", bands*perBand, len(items))
+	}
+
+	for i, item := range items {
+		expected := fmt.Sprintf("%d-%02d", i/perBand, i%perBand)
+
+		if actual := item.(*mockItem).value; actual != expected {
+			t.Fatalf("expected %s at %d, got %s", expected, i, actual)
+		}
+	}
+}
+
+func TestPriorityQueueOf_Stable(t *testing.T) {
+	type job struct {
+		level int
+		id    int
+	}
+
+	q := NewPriorityQueueOf(0, func(a, b job) bool { return a.level > b.level })
+	q.SetStable(true)
+
+	for i := 0; i < 100; i++ {
+		q.Push(job{level: i % 3, id: i})
+	}
+
+	last := map[int]int{}
+
+	for _, j := range q.Drain() {
+		if prev, ok := last[j.level]; ok && prev > j.id {
+			t.Fatalf("expected FIFO order within level %d, got %d after %d", j.level, j.id, prev)
+		}
+
+		last[j.level] = j.id
+	}
+}
+
+func TestPriorityQueueOf_SetStableReorders(t *testing.T) {
+	type job struct {
+		level int
+		id    int
+	}
+
+	q := NewPriorityQueueOf(0, func(a, b job) bool { return a.level < b.level })
+
+	for i := 0; i < 32; i++ {
+		q.Push(job{level: 0, id: i})
+	}
+
+	q.SetStable(true)
+
+	for i := 0; i < 32; i++ {
+		v, err := q.Pop()
+		if err != nil {
+			t.Fatalf("failed to pop queue: %v", err)
+		}
+
+		if v.id != i {
+			t.Fatalf("expected %d, got %d", i, v.id)
+		}
+	}
+}
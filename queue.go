@@ -1,55 +1,78 @@
 package xtypes
 
 import (
+	"context"
 	"sync"
 )
 
-// Queue is a simple queue based on slice.
+// QueueOf is a simple generic queue based on a growable circular buffer.
 //
+// Slots freed by Pop are reused in place, and the buffer shrinks after sustained low occupancy,
+// so a long-lived queue does not retain memory it no longer needs.
+// A bounded queue holds at most limit items and applies its OverflowPolicy when it is full.
 // This is clean and simple thread safe implementation with no magic.
-// Queue MUST be created using constructor.
-type Queue struct {
-	mu    sync.Mutex
-	items QItems
+// QueueOf MUST be created using constructor.
+type QueueOf[T any] struct {
+	mu        sync.Mutex
+	items     ring[T]
+	consumers waiters // Consumers blocked in PopWait and GetWait.
+	producers waiters // Producers blocked on a full queue.
+	closed    bool
+	limit     int // Zero means unbounded.
+	policy    OverflowPolicy
+	dropped   uint64
 }
 
-// NewQueue creates and inits a new Queue.
-func NewQueue(hint int) *Queue {
-	q := &Queue{
-		items: make(QItems, 0, hint),
-	}
+// NewQueueOf creates and inits a new QueueOf.
+func NewQueueOf[T any](hint int) *QueueOf[T] {
+	q := &QueueOf[T]{}
+	q.init(hint, 0, OverflowReject)
+
+	return q
+}
+
+// NewBoundedQueueOf creates and inits a new QueueOf which holds at most limit items.
+//
+// A limit less than 1 means the queue is unbounded.
+func NewBoundedQueueOf[T any](hint, limit int, policy OverflowPolicy) *QueueOf[T] {
+	q := &QueueOf[T]{}
+	q.init(hint, limit, policy)
 
 	return q
 }
 
 // Get returns up to requested n of items.
-func (q *Queue) Get(n int) ([]interface{}, error) {
+func (q *QueueOf[T]) Get(n int) ([]T, error) {
 	if n < 1 {
-		return []interface{}{}, nil
+		return []T{}, nil
 	}
 
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if q.items == nil {
+	if !q.items.valid() {
 		return nil, ErrInvalidQueue
 	}
 
-	result := make([]interface{}, 0, n)
-
-	for i := 0; i < n; i++ {
-		if len(q.items) == 0 {
-			break
-		}
-
-		result = append(result, q.items.Pop())
+	if q.closed && q.items.len() == 0 {
+		return nil, ErrClosed
 	}
 
-	return result, nil
+	return q.get(n), nil
 }
 
 // Put adds items to queue.
-func (q *Queue) Put(items ...interface{}) error {
+//
+// If the queue is bounded, the overflow policy is applied to each item.
+// With OverflowReject no items are added unless all of them fit.
+// With OverflowBlock Put waits for space, see PutWait.
+func (q *QueueOf[T]) Put(items ...T) error {
+	return q.PutWait(context.Background(), items...)
+}
+
+// PutWait is Put which gives up waiting for space once ctx is done and returns ctx.Err().
+// Items added before the cancellation stay in the queue.
+func (q *QueueOf[T]) PutWait(ctx context.Context, items ...T) error {
 	if len(items) == 0 {
 		return nil
 	}
@@ -57,72 +80,310 @@ func (q *Queue) Put(items ...interface{}) error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if q.items == nil {
+	if !q.items.valid() {
 		return ErrInvalidQueue
 	}
 
+	if q.closed {
+		return ErrClosed
+	}
+
+	if q.rejects(len(items)) {
+		return ErrFull
+	}
+
 	for _, item := range items {
-		q.items.Push(item)
+		if err := q.push(ctx, item); err != nil {
+			return err
+		}
 	}
 
 	return nil
 }
 
-// Pop returns the first element from the queue. If the queue is empty - an error will be returned.
-func (q *Queue) Pop() (interface{}, error) {
+// Pop returns the first element from the queue. If the queue is empty - an error will be returned,
+// ErrClosed if the queue has been closed, ErrEmptyQueue otherwise.
+func (q *QueueOf[T]) Pop() (T, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.items.len() == 0 {
+		var zero T
+
+		if q.closed {
+			return zero, ErrClosed
+		}
+
+		return zero, ErrEmptyQueue
+	}
+
+	return q.pop(), nil
+}
+
+// GetWait returns up to requested n of items.
+// If the queue is empty it blocks until an item is added, the queue is closed or ctx is done.
+// ErrClosed is returned once the queue is closed and has no items left, ctx.Err() is returned on cancellation.
+func (q *QueueOf[T]) GetWait(ctx context.Context, n int) ([]T, error) {
+	if n < 1 {
+		return []T{}, nil
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.items.valid() {
+		return nil, ErrInvalidQueue
+	}
+
+	if err := q.consumers.wait(ctx, &q.mu, q.ready); err != nil {
+		return nil, err
+	}
+
+	if q.items.len() == 0 {
+		return nil, ErrClosed
+	}
+
+	return q.get(n), nil
+}
+
+// PopWait returns the first element from the queue.
+// If the queue is empty it blocks until an item is added, the queue is closed or ctx is done.
+// ErrClosed is returned once the queue is closed and has no items left, ctx.Err() is returned on cancellation.
+func (q *QueueOf[T]) PopWait(ctx context.Context) (T, error) {
+	var zero T
+
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if len(q.items) == 0 {
-		return nil, ErrEmptyQueue
+	if !q.items.valid() {
+		return zero, ErrInvalidQueue
+	}
+
+	if err := q.consumers.wait(ctx, &q.mu, q.ready); err != nil {
+		return zero, err
 	}
 
-	return q.items.Pop(), nil
+	if q.items.len() == 0 {
+		return zero, ErrClosed
+	}
+
+	return q.pop(), nil
 }
 
 // Push adds an item to the queue. If the underlying storage is nil - an error will be returned.
-func (q *Queue) Push(x interface{}) error {
+//
+// If the queue is bounded and full, the overflow policy is applied.
+// With OverflowBlock Push waits for space, see PushWait.
+func (q *QueueOf[T]) Push(x T) error {
+	return q.PushWait(context.Background(), x)
+}
+
+// PushWait is Push which gives up waiting for space once ctx is done and returns ctx.Err().
+func (q *QueueOf[T]) PushWait(ctx context.Context, x T) error {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if q.items == nil {
+	if !q.items.valid() {
 		return ErrInvalidQueue
 	}
 
-	q.items.Push(x)
+	if q.closed {
+		return ErrClosed
+	}
 
-	return nil
+	return q.push(ctx, x)
 }
 
 // Peek returns the first element without modifying the queue.
-func (q *Queue) Peek() interface{} {
+// If the queue is empty - the zero value of T is returned.
+func (q *QueueOf[T]) Peek() T {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	if len(q.items) == 0 {
-		return nil
+	if q.items.len() == 0 {
+		var zero T
+
+		return zero
 	}
 
-	return q.items[0]
+	return q.items.peek()
 }
 
 // Len returns the len of the queue.
-func (q *Queue) Len() int {
+func (q *QueueOf[T]) Len() int {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	return len(q.items)
+	return q.items.len()
 }
 
 // Empty returns true if the queue is empty.
-func (q *Queue) Empty() bool {
+func (q *QueueOf[T]) Empty() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.items.len() == 0
+}
+
+// Policy returns the overflow policy of the queue.
+func (q *QueueOf[T]) Policy() OverflowPolicy {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.policy
+}
+
+// Dropped returns the number of items removed by the overflow policy to make room.
+// Items rejected with ErrFull are not counted.
+func (q *QueueOf[T]) Dropped() uint64 {
 	q.mu.Lock()
 	defer q.mu.Unlock()
 
-	return len(q.items) == 0
+	return q.dropped
+}
+
+// Close closes the queue.
+//
+// Push and Put return ErrClosed afterwards, blocked consumers and producers are woken up.
+// Items left in the queue can still be consumed or taken with Drain.
+// Closing a closed queue returns ErrClosed.
+func (q *QueueOf[T]) Close() error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if !q.items.valid() {
+		return ErrInvalidQueue
+	}
+
+	if q.closed {
+		return ErrClosed
+	}
+
+	q.closed = true
+	q.consumers.broadcast()
+	q.producers.broadcast()
+
+	return nil
+}
+
+// Drain removes and returns all items left in the queue in the order they would have been popped.
+func (q *QueueOf[T]) Drain() []T {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return q.get(q.items.len())
+}
+
+// init prepares the storage, a limit less than 1 means the queue is unbounded.
+func (q *QueueOf[T]) init(hint, limit int, policy OverflowPolicy) {
+	if limit > 0 {
+		hint = min(hint, limit)
+	}
+
+	q.items = newRing[T](hint)
+	q.limit = max(limit, 0)
+	q.policy = policy
+}
+
+// push adds x to the queue applying the overflow policy.
+func (q *QueueOf[T]) push(ctx context.Context, x T) error {
+	if q.full() {
+		switch q.policy {
+		case OverflowBlock:
+			if err := q.producers.wait(ctx, &q.mu, q.room); err != nil {
+				return err
+			}
+
+			if q.closed {
+				return ErrClosed
+			}
+		case OverflowDropOldest:
+			q.items.pop()
+			q.dropped++
+		default:
+			return ErrFull
+		}
+	}
+
+	q.items.push(x)
+	q.consumers.signal(1)
+
+	return nil
+}
+
+// pop removes the first item and wakes a blocked producer.
+func (q *QueueOf[T]) pop() T {
+	item := q.items.pop()
+	q.producers.signal(1)
+
+	return item
+}
+
+// get removes up to n items.
+func (q *QueueOf[T]) get(n int) []T {
+	result := make([]T, 0, min(n, q.items.len()))
+
+	for i := 0; i < n && q.items.len() > 0; i++ {
+		result = append(result, q.pop())
+	}
+
+	return result
+}
+
+// full reports whether the queue has reached its limit.
+func (q *QueueOf[T]) full() bool {
+	return q.limit > 0 && q.items.len() >= q.limit
+}
+
+// rejects reports whether adding n items is rejected as a whole.
+func (q *QueueOf[T]) rejects(n int) bool {
+	switch q.policy {
+	case OverflowBlock, OverflowDropOldest:
+		return false
+	default:
+		return q.limit > 0 && q.items.len()+n > q.limit
+	}
+}
+
+// ready reports whether a waiting consumer can proceed.
+func (q *QueueOf[T]) ready() bool {
+	return q.items.len() > 0 || q.closed
+}
+
+// room reports whether a waiting producer can proceed.
+func (q *QueueOf[T]) room() bool {
+	return !q.full() || q.closed
+}
+
+// Queue is a simple queue of arbitrary values.
+//
+// It is a thin wrapper around QueueOf kept for compatibility.
+// Queue MUST be created using constructor.
+type Queue struct {
+	QueueOf[interface{}]
+}
+
+// NewQueue creates and inits a new Queue.
+func NewQueue(hint int) *Queue {
+	q := &Queue{}
+	q.init(hint, 0, OverflowReject)
+
+	return q
+}
+
+// NewBoundedQueue creates and inits a new Queue which holds at most limit items.
+//
+// A limit less than 1 means the queue is unbounded.
+func NewBoundedQueue(hint, limit int, policy OverflowPolicy) *Queue {
+	q := &Queue{}
+	q.init(hint, limit, policy)
+
+	return q
 }
 
 // QItems represents the queue items.
+//
+// It is kept for compatibility, Queue does not use it.
 type QItems []interface{}
 
 // Pop returns the first item.
@@ -2,8 +2,11 @@ package xtypes
 
 import (
 	"container/list"
+	"context"
 	"reflect"
+	"sync"
 	"testing"
+	"time"
 )
 
 const (
@@ -215,6 +218,415 @@ func TestQueue_Empty(t *testing.T) {
 	}
 }
 
+func TestQueueOf_Order(t *testing.T) {
+	q := NewQueueOf[string](2)
+
+	if err := q.Put("a", "b", "c"); err != nil {
+		t.Fatalf("failed to put to queue: %v", err)
+	}
+
+	q.Push("d")
+
+	if v := q.Peek(); v != "a" {
+		t.Fatalf("expected %s, got %s", "a", v)
+	}
+
+	v, err := q.Pop()
+	if err != nil {
+		t.Fatalf("failed to pop queue: %v", err)
+	}
+
+	if v != "a" {
+		t.Fatalf("expected %s, got %s", "a", v)
+	}
+
+	expected := []string{"b", "c", "d"}
+
+	actual, err := q.Get(q.Len() + 1)
+	if err != nil {
+		t.Fatalf("failed to get from queue: %v", err)
+	}
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+}
+
+func TestQueueOf_PopEmpty(t *testing.T) {
+	q := NewQueueOf[int](1)
+
+	v, err := q.Pop()
+	if err != ErrEmptyQueue {
+		t.Fatalf("expected %v, got %v ", ErrEmptyQueue, err)
+	}
+
+	if v != 0 {
+		t.Fatalf("expected zero value, got %d", v)
+	}
+
+	if v := q.Peek(); v != 0 {
+		t.Fatalf("expected zero value, got %d", v)
+	}
+}
+
+func TestQueueOf_Invalid(t *testing.T) {
+	q := &QueueOf[int]{}
+
+	expected := ErrInvalidQueue
+
+	if err := q.Push(1); err != expected {
+		t.Fatalf("expected %v, got %v ", expected, err)
+	}
+
+	if err := q.Put(1, 2); err != expected {
+		t.Fatalf("expected %v, got %v ", expected, err)
+	}
+
+	if _, err := q.Get(1); err != expected {
+		t.Fatalf("expected %v, got %v ", expected, err)
+	}
+}
+
+func TestQueueOf_PopWait(t *testing.T) {
+	q := NewQueueOf[int](1)
+
+	result := make(chan int)
+
+	go func() {
+		v, err := q.PopWait(context.Background())
+		if err != nil {
+			t.Errorf("failed to pop queue: %v", err)
+		}
+
+		result <- v
+	}()
+
+	q.Push(42)
+
+	if v := <-result; v != 42 {
+		t.Fatalf("expected %d, got %d", 42, v)
+	}
+}
+
+func TestQueueOf_PopWaitCancel(t *testing.T) {
+	q := NewQueueOf[int](1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if _, err := q.PopWait(ctx); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if n := q.consumers.len(); n != 0 {
+		t.Fatalf("expected %d waiters, got %d", 0, n)
+	}
+}
+
+func TestQueueOf_PopWaitMany(t *testing.T) {
+	q := NewQueueOf[int](1)
+
+	const consumers = 16
+
+	var wg sync.WaitGroup
+
+	result := make(chan int, consumers)
+
+	for i := 0; i < consumers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			v, err := q.PopWait(context.Background())
+			if err != nil {
+				t.Errorf("failed to pop queue: %v", err)
+			}
+
+			result <- v
+		}()
+	}
+
+	for i := 0; i < consumers/2; i++ {
+		q.Push(i)
+	}
+
+	q.Put(8, 9, 10, 11, 12, 13, 14, 15)
+
+	wg.Wait()
+	close(result)
+
+	sum := 0
+	for v := range result {
+		sum += v
+	}
+
+	if expected := consumers * (consumers - 1) / 2; sum != expected {
+		t.Fatalf("expected %d, got %d", expected, sum)
+	}
+}
+
+func TestQueueOf_GetWait(t *testing.T) {
+	q := NewQueueOf[string](1)
+
+	result := make(chan []string)
+
+	go func() {
+		v, err := q.GetWait(context.Background(), 3)
+		if err != nil {
+			t.Errorf("failed to get from queue: %v", err)
+		}
+
+		result <- v
+	}()
+
+	q.Put("a", "b")
+
+	v := <-result
+	if len(v) == 0 || len(v) > 2 || v[0] != "a" {
+		t.Fatalf("expected a prefix of %v, got %v", []string{"a", "b"}, v)
+	}
+}
+
+func TestQueueOf_GetWaitCancel(t *testing.T) {
+	q := NewQueueOf[string](1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := q.GetWait(ctx, 1); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	if _, err := (&QueueOf[string]{}).GetWait(ctx, 1); err != ErrInvalidQueue {
+		t.Fatalf("expected %v, got %v", ErrInvalidQueue, err)
+	}
+}
+
+func TestQueueOf_Close(t *testing.T) {
+	q := NewQueueOf[int](1)
+
+	q.Put(1, 2)
+
+	if err := q.Close(); err != nil {
+		t.Fatalf("failed to close queue: %v", err)
+	}
+
+	if err := q.Close(); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+
+	if err := q.Push(3); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+
+	if err := q.Put(3, 4); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+
+	// Items added before Close are still available.
+	v, err := q.PopWait(context.Background())
+	if err != nil {
+		t.Fatalf("failed to pop queue: %v", err)
+	}
+
+	if v != 1 {
+		t.Fatalf("expected %d, got %d", 1, v)
+	}
+
+	if v, err := q.Pop(); err != nil || v != 2 {
+		t.Fatalf("expected %d, got %d: %v", 2, v, err)
+	}
+
+	if _, err := q.Pop(); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+
+	if _, err := q.Get(1); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+
+	if _, err := q.GetWait(context.Background(), 1); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+}
+
+func TestQueueOf_CloseWakesWaiters(t *testing.T) {
+	q := NewQueueOf[int](1)
+
+	const consumers = 4
+
+	errs := make(chan error, consumers)
+
+	for i := 0; i < consumers; i++ {
+		go func() {
+			_, err := q.PopWait(context.Background())
+			errs <- err
+		}()
+	}
+
+	q.Close()
+
+	for i := 0; i < consumers; i++ {
+		if err := <-errs; err != ErrClosed {
+			t.Fatalf("expected %v, got %v", ErrClosed, err)
+		}
+	}
+}
+
+func TestQueueOf_Drain(t *testing.T) {
+	q := NewQueueOf[int](1)
+
+	q.Put(1, 2, 3)
+	q.Close()
+
+	expected := []int{1, 2, 3}
+	if actual := q.Drain(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	if !q.Empty() {
+		t.Fatalf("expected empty queue, got non-empty")
+	}
+
+	if actual := q.Drain(); len(actual) != 0 {
+		t.Fatalf("expected empty slice, got %v", actual)
+	}
+}
+
+func TestQueue_CloseInvalid(t *testing.T) {
+	q := &Queue{}
+
+	if err := q.Close(); err != ErrInvalidQueue {
+		t.Fatalf("expected %v, got %v", ErrInvalidQueue, err)
+	}
+}
+
+func TestQueueOf_BoundedReject(t *testing.T) {
+	q := NewBoundedQueueOf[int](8, 2, OverflowReject)
+
+	if err := q.Put(1, 2, 3); err != ErrFull {
+		t.Fatalf("expected %v, got %v", ErrFull, err)
+	}
+
+	if l := q.Len(); l != 0 {
+		t.Fatalf("expected %d, got %d", 0, l)
+	}
+
+	if err := q.Put(1, 2); err != nil {
+		t.Fatalf("failed to put to queue: %v", err)
+	}
+
+	if err := q.Push(3); err != ErrFull {
+		t.Fatalf("expected %v, got %v", ErrFull, err)
+	}
+
+	if d := q.Dropped(); d != 0 {
+		t.Fatalf("expected %d, got %d", 0, d)
+	}
+
+	if p := q.Policy(); p != OverflowReject {
+		t.Fatalf("expected %v, got %v", OverflowReject, p)
+	}
+}
+
+func TestQueueOf_BoundedDropOldest(t *testing.T) {
+	q := NewBoundedQueueOf[int](0, 3, OverflowDropOldest)
+
+	if err := q.Put(1, 2, 3, 4, 5); err != nil {
+		t.Fatalf("failed to put to queue: %v", err)
+	}
+
+	q.Push(6)
+
+	expected := []int{4, 5, 6}
+	if actual := q.Drain(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %v, got %v", expected, actual)
+	}
+
+	if d := q.Dropped(); d != 3 {
+		t.Fatalf("expected %d, got %d", 3, d)
+	}
+}
+
+func TestQueueOf_BoundedBlock(t *testing.T) {
+	q := NewBoundedQueueOf[int](0, 1, OverflowBlock)
+
+	q.Push(1)
+
+	done := make(chan error)
+
+	go func() {
+		done <- q.Put(2, 3)
+	}()
+
+	for _, expected := range []int{1, 2, 3} {
+		v, err := q.PopWait(context.Background())
+		if err != nil {
+			t.Fatalf("failed to pop queue: %v", err)
+		}
+
+		if v != expected {
+			t.Fatalf("expected %d, got %d", expected, v)
+		}
+	}
+
+	if err := <-done; err != nil {
+		t.Fatalf("failed to put to queue: %v", err)
+	}
+}
+
+func TestQueueOf_BoundedBlockCancel(t *testing.T) {
+	q := NewBoundedQueueOf[int](0, 1, OverflowBlock)
+
+	q.Push(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := q.PushWait(ctx, 2); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	if n := q.producers.len(); n != 0 {
+		t.Fatalf("expected %d waiters, got %d", 0, n)
+	}
+
+	done := make(chan error)
+
+	go func() {
+		done <- q.Push(2)
+	}()
+
+	q.Close()
+
+	if err := <-done; err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+}
+
+func TestQueueOf_BoundedEvictLowest(t *testing.T) {
+	q := NewBoundedQueueOf[int](0, 1, OverflowEvictLowest)
+
+	q.Push(1)
+
+	if err := q.Push(2); err != ErrFull {
+		t.Fatalf("expected %v, got %v", ErrFull, err)
+	}
+}
+
+func TestQueue_Bounded(t *testing.T) {
+	q := NewBoundedQueue(4, 1, OverflowReject)
+
+	if err := q.Push(&mockItem{value: "put 1"}); err != nil {
+		t.Fatalf("failed to push to queue: %v", err)
+	}
+
+	if err := q.Push(&mockItem{value: "put 2"}); err != ErrFull {
+		t.Fatalf("expected %v, got %v", ErrFull, err)
+	}
+}
+
 // Benchmarks.
 func BenchmarkQueuePush(b *testing.B) {
 	for i := 0; i < b.N; i++ {
@@ -236,6 +648,91 @@ func BenchmarkQueuePushHint(b *testing.B) {
 	}
 }
 
+func BenchmarkQueueOfPush(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		q := NewQueueOf[int](0)
+
+		for n := 0; n < size; n++ {
+			q.Push(n)
+		}
+	}
+}
+
+// BenchmarkQueueSteady tests long-lived traffic where the queue holds a backlog and items are pushed and popped in turns.
+func BenchmarkQueueSteady(b *testing.B) {
+	q := NewQueue(0)
+
+	for n := 0; n < size; n++ {
+		q.Push(n)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		q.Push(i)
+		q.Pop()
+	}
+}
+
+// BenchmarkQItemsSteady is BenchmarkQueueSteady for the previous slice-based storage.
+//
+// Pop reslices the front of the array away, so appending keeps reallocating.
+func BenchmarkQItemsSteady(b *testing.B) {
+	var mu sync.Mutex
+
+	q := make(QItems, 0)
+
+	for n := 0; n < size; n++ {
+		q.Push(n)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		mu.Lock()
+		q.Push(i)
+		mu.Unlock()
+
+		mu.Lock()
+		q.Pop()
+		mu.Unlock()
+	}
+}
+
+func BenchmarkQueuePushPop(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		q := NewQueue(0)
+
+		for n := 0; n < size; n++ {
+			q.Push(n)
+		}
+
+		for n := 0; n < size; n++ {
+			q.Pop()
+		}
+	}
+}
+
+func BenchmarkQItemsPushPop(b *testing.B) {
+	var mu sync.Mutex
+
+	for i := 0; i < b.N; i++ {
+		q := make(QItems, 0)
+
+		for n := 0; n < size; n++ {
+			mu.Lock()
+			q.Push(n)
+			mu.Unlock()
+		}
+
+		for n := 0; n < size; n++ {
+			mu.Lock()
+			q.Pop()
+			mu.Unlock()
+		}
+	}
+}
+
 // BenchmarkListPush tests a queue based on built-in list.
 //
 // Yes, it looks like it is faster but with a simple exception - it isn't thread safe.
@@ -0,0 +1,121 @@
+package xtypes
+
+const (
+	// ringMinCap is the capacity below which a ring buffer never shrinks.
+	ringMinCap = 16
+)
+
+// ring is a growable circular buffer.
+//
+// Freed slots are reused in place, so steady push/pop traffic does not reallocate.
+// The buffer doubles when full and halves after sustained low occupancy.
+// This is NOT thread safe.
+type ring[T any] struct {
+	buf  []T
+	head int // Index of the first item.
+	size int // Number of items.
+	min  int // Capacity the buffer never shrinks below.
+	low  int // Number of consecutive pops with low occupancy.
+}
+
+// newRing returns a ring buffer with preallocated hint slots.
+func newRing[T any](hint int) ring[T] {
+	if hint < 0 {
+		hint = 0
+	}
+
+	return ring[T]{
+		buf: make([]T, hint),
+		min: max(hint, ringMinCap),
+	}
+}
+
+// valid returns true if the buffer has been created with the constructor.
+func (r *ring[T]) valid() bool {
+	return r.buf != nil
+}
+
+// len returns the number of items in the buffer.
+func (r *ring[T]) len() int {
+	return r.size
+}
+
+// push inserts the x at the end of the buffer.
+func (r *ring[T]) push(x T) {
+	if r.size == len(r.buf) {
+		r.resize(max(2*len(r.buf), ringMinCap))
+	}
+
+	r.buf[r.index(r.size)] = x
+	r.size++
+}
+
+// pop removes and returns the first item.
+// The buffer MUST NOT be empty.
+func (r *ring[T]) pop() T {
+	item := r.buf[r.head]
+
+	// Prevent leaks.
+	var zero T
+	r.buf[r.head] = zero
+
+	r.head = r.index(1)
+	r.size--
+
+	if r.size == 0 {
+		r.head = 0
+	}
+
+	r.shrink()
+
+	return item
+}
+
+// peek returns the first item.
+// The buffer MUST NOT be empty.
+func (r *ring[T]) peek() T {
+	return r.buf[r.head]
+}
+
+// index returns the position in buf of the i-th item.
+func (r *ring[T]) index(i int) int {
+	i += r.head
+	if i >= len(r.buf) {
+		i -= len(r.buf)
+	}
+
+	return i
+}
+
+// shrink halves the buffer once occupancy stayed at a quarter of capacity or below
+// for as many pops as there are slots.
+func (r *ring[T]) shrink() {
+	n := len(r.buf)
+	if n <= r.min || r.size > n/4 {
+		r.low = 0
+		return
+	}
+
+	r.low++
+	if r.low < n {
+		return
+	}
+
+	r.low = 0
+	r.resize(max(n/2, r.min))
+}
+
+// resize moves items to a new buffer of size n.
+func (r *ring[T]) resize(n int) {
+	buf := make([]T, n)
+
+	if r.head+r.size <= len(r.buf) {
+		copy(buf, r.buf[r.head:r.head+r.size])
+	} else {
+		k := copy(buf, r.buf[r.head:])
+		copy(buf[k:], r.buf[:r.size-k])
+	}
+
+	r.buf = buf
+	r.head = 0
+}
@@ -0,0 +1,118 @@
+package xtypes
+
+import (
+	"testing"
+)
+
+func TestRing_WrapAround(t *testing.T) {
+	r := newRing[int](4)
+
+	for i := 0; i < 3; i++ {
+		r.push(i)
+	}
+
+	for i := 0; i < 2; i++ {
+		if v := r.pop(); v != i {
+			t.Fatalf("expected %d, got %d", i, v)
+		}
+	}
+
+	for i := 3; i < 6; i++ {
+		r.push(i)
+	}
+
+	if c := len(r.buf); c != 4 {
+		t.Fatalf("expected capacity %d, got %d", 4, c)
+	}
+
+	for i := 2; i < 6; i++ {
+		if v := r.pop(); v != i {
+			t.Fatalf("expected %d, got %d", i, v)
+		}
+	}
+
+	if l := r.len(); l != 0 {
+		t.Fatalf("expected %d, got %d", 0, l)
+	}
+}
+
+func TestRing_Grow(t *testing.T) {
+	r := newRing[int](0)
+
+	if !r.valid() {
+		t.Fatal("expected valid ring")
+	}
+
+	// Move the head so that growing has to unwrap items.
+	for i := 0; i < ringMinCap; i++ {
+		r.push(i)
+	}
+
+	r.pop()
+	r.push(ringMinCap)
+	r.push(ringMinCap + 1)
+
+	if c := len(r.buf); c != 2*ringMinCap {
+		t.Fatalf("expected capacity %d, got %d", 2*ringMinCap, c)
+	}
+
+	for i := 1; i < ringMinCap+2; i++ {
+		if v := r.pop(); v != i {
+			t.Fatalf("expected %d, got %d", i, v)
+		}
+	}
+}
+
+func TestRing_Shrink(t *testing.T) {
+	r := newRing[int](0)
+
+	n := 8 * ringMinCap
+	for i := 0; i < n; i++ {
+		r.push(i)
+	}
+
+	for i := 0; i < n-1; i++ {
+		r.pop()
+	}
+
+	// Sustained traffic with a single item in the buffer.
+	for i := 0; i < 4*n; i++ {
+		r.push(i)
+		r.pop()
+	}
+
+	if c := len(r.buf); c != ringMinCap {
+		t.Fatalf("expected capacity %d, got %d", ringMinCap, c)
+	}
+
+	if l := r.len(); l != 1 {
+		t.Fatalf("expected %d, got %d", 1, l)
+	}
+}
+
+func TestRing_ShrinkHint(t *testing.T) {
+	hint := 4 * ringMinCap
+	r := newRing[int](hint)
+
+	for i := 0; i < 4*hint; i++ {
+		r.push(i)
+		r.pop()
+	}
+
+	if c := len(r.buf); c != hint {
+		t.Fatalf("expected capacity %d, got %d", hint, c)
+	}
+}
+
+func TestRing_PopReleases(t *testing.T) {
+	r := newRing[*mockItem](2)
+
+	r.push(&mockItem{value: "put 1"})
+	r.pop()
+
+	for i, v := range r.buf {
+		if v != nil {
+			t.Fatalf("expected nil at %d, got %v", i, v)
+		}
+	}
+}
@@ -5,124 +5,261 @@ import (
 	"sync"
 )
 
-// SafeMap provides a storage based on a map.
+// SafeMapOf provides a generic storage based on a map.
 //
 // It is safe to use in concurrent mode.
-// The storage is protected by the mutex.
-type SafeMap struct {
-	mu      sync.Mutex // Protects storage below
-	storage map[string]interface{}
+// The storage is protected by a reader/writer mutex, so Get, Len and Keys run concurrently.
+// Keys and Drain return data sorted by key if the map has been created with a key ordering,
+// otherwise the order is unspecified.
+type SafeMapOf[K comparable, V any] struct {
+	mu       sync.RWMutex // Protects storage below
+	storage  map[K]V
+	less     func(a, b K) bool
+	watchers map[*MapWatch[K, V]]struct{}
+	pending  []watchEvent[K, V]
+	ticket   uint64 // Turn of the next delivery of pending events.
+
+	sendMu   sync.Mutex // Protects fields below, never taken while waiting for mu.
+	sendCond *sync.Cond // Signalled when a delivery is over, created with the first watch.
+	sent     uint64     // Turn of the delivery which may go now.
 }
 
-// NewSafeMap returns a ready to use instance of SafeMap.
-func NewSafeMap() *SafeMap {
-	return &SafeMap{
-		storage: make(map[string]interface{}),
+// NewSafeMapOf returns a ready to use instance of SafeMapOf.
+//
+// The less function defines the order of keys for Keys and Drain, it can be nil.
+func NewSafeMapOf[K comparable, V any](less func(a, b K) bool) *SafeMapOf[K, V] {
+	return &SafeMapOf[K, V]{
+		storage: make(map[K]V),
+		less:    less,
 	}
 }
 
 // Get returns object.
-func (s *SafeMap) Get(key string) (interface{}, bool) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *SafeMapOf[K, V]) Get(key K) (V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	return s.get(key)
 }
 
 // Set sets the object.
-func (s *SafeMap) Set(key string, value interface{}) error {
+func (s *SafeMapOf[K, V]) Set(key K, value V) error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	return s.set(key, value)
 }
 
 // Del deletes the object.
-func (s *SafeMap) Del(key string) {
+func (s *SafeMapOf[K, V]) Del(key K) {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	s.del(key)
 }
 
 // Drain returns all elements as slice and removes keys from the storage.
-// Elements are sorted by key.
-func (s *SafeMap) Drain() []interface{} {
+// Elements are sorted by key if the key ordering is set.
+func (s *SafeMapOf[K, V]) Drain() []V {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	defer s.unlock()
 
 	return s.drain()
 }
 
 // Len returns count of elements in the storage.
-func (s *SafeMap) Len() int {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *SafeMapOf[K, V]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	return s.len()
 }
 
 // Keys returns all the keys as a slice.
-func (s *SafeMap) Keys() []string {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// Keys are sorted if the key ordering is set.
+func (s *SafeMapOf[K, V]) Keys() []K {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
 	return s.keys()
 }
 
+// GetOrSet returns the existing value for the key if present, loaded is true then.
+// Otherwise it sets and returns the given value.
+func (s *SafeMapOf[K, V]) GetOrSet(key K, value V) (actual V, loaded bool) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	if r, ok := s.get(key); ok {
+		return r, true
+	}
+
+	s.set(key, value)
+
+	return value, false
+}
+
+// CompareAndSwap sets the new value for the key if its current value is equal to old.
+//
+// Values are compared with ==, it panics if V is not comparable, e.g. a slice or a map.
+func (s *SafeMapOf[K, V]) CompareAndSwap(key K, old, new V) bool {
+	s.mu.Lock()
+	defer s.unlock()
+
+	if r, ok := s.get(key); !ok || any(r) != any(old) {
+		return false
+	}
+
+	s.set(key, new)
+
+	return true
+}
+
+// CompareAndDelete deletes the key if its current value is equal to old.
+//
+// Values are compared with ==, it panics if V is not comparable, e.g. a slice or a map.
+func (s *SafeMapOf[K, V]) CompareAndDelete(key K, old V) bool {
+	s.mu.Lock()
+	defer s.unlock()
+
+	if r, ok := s.get(key); !ok || any(r) != any(old) {
+		return false
+	}
+
+	s.del(key)
+
+	return true
+}
+
+// Update replaces the value of the key with the result of fn.
+//
+// The fn is called with the current value and whether the key is present.
+// If it returns keep as false, the key is deleted. The resulting value and keep are returned.
+// The fn runs under the lock, it MUST NOT call methods of the map.
+func (s *SafeMapOf[K, V]) Update(key K, fn func(old V, ok bool) (new V, keep bool)) (V, bool) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	r, keep := fn(s.get(key))
+	if !keep {
+		s.del(key)
+
+		return r, false
+	}
+
+	s.set(key, r)
+
+	return r, true
+}
+
+// GetAndDelete deletes the key and returns its previous value if present.
+func (s *SafeMapOf[K, V]) GetAndDelete(key K) (V, bool) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	r, ok := s.get(key)
+	if ok {
+		s.del(key)
+	}
+
+	return r, ok
+}
+
 // get returns requested value and flag.
-func (s *SafeMap) get(key string) (interface{}, bool) {
+func (s *SafeMapOf[K, V]) get(key K) (V, bool) {
 	r, ok := s.storage[key]
 
 	return r, ok
 }
 
 // set sets value in the storage by key.
-func (s *SafeMap) set(key string, value interface{}) error {
+func (s *SafeMapOf[K, V]) set(key K, value V) error {
+	if len(s.watchers) == 0 {
+		s.storage[key] = value
+
+		return nil
+	}
+
+	old, ok := s.storage[key]
 	s.storage[key] = value
 
+	s.queue(MapEvent[K, V]{Type: MapEventSet, Key: key, Old: old, New: value, HadOld: ok})
+
 	return nil
 }
 
 // del deletes the key from the storage.
-func (s *SafeMap) del(key string) {
-	delete(s.storage, key)
-}
+func (s *SafeMapOf[K, V]) del(key K) {
+	if len(s.watchers) == 0 {
+		delete(s.storage, key)
 
-// drain returns values as a slice and removes data from the storage.
-// values in the resulted slice are sorted by key.
-func (s *SafeMap) drain() []interface{} {
-	data := make([]interface{}, 0, len(s.storage))
-	keys := make([]string, 0, len(s.storage))
+		return
+	}
 
-	for k := range s.storage {
-		keys = append(keys, k)
+	old, ok := s.storage[key]
+	if !ok {
+		return
 	}
 
-	sort.Strings(keys)
+	delete(s.storage, key)
+
+	s.queue(MapEvent[K, V]{Type: MapEventDel, Key: key, Old: old, HadOld: true})
+}
+
+// drain returns values as a slice and removes data from the storage.
+// values in the resulted slice are sorted by key if the key ordering is set.
+func (s *SafeMapOf[K, V]) drain() []V {
+	data := make([]V, 0, len(s.storage))
 
-	for _, k := range keys {
+	for _, k := range s.keys() {
 		data = append(data, s.storage[k])
-		delete(s.storage, k)
+		s.del(k)
 	}
 
 	return data
 }
 
 // len returns len of the storage.
-func (s *SafeMap) len() int {
+func (s *SafeMapOf[K, V]) len() int {
 	return len(s.storage)
 }
 
 // keys returns a slice of keys.
-func (s *SafeMap) keys() []string {
-	keys := make([]string, 0, len(s.storage))
+func (s *SafeMapOf[K, V]) keys() []K {
+	keys := make([]K, 0, len(s.storage))
 
 	for k := range s.storage {
 		keys = append(keys, k)
 	}
 
-	sort.Strings(keys)
+	if s.less != nil {
+		sort.Slice(keys, func(i, j int) bool {
+			return s.less(keys[i], keys[j])
+		})
+	}
 
 	return keys
 }
+
+// SafeMap provides a storage based on a map with string keys.
+//
+// It is a thin wrapper around SafeMapOf kept for compatibility.
+// Keys and Drain return data sorted by key.
+type SafeMap struct {
+	SafeMapOf[string, interface{}]
+}
+
+// NewSafeMap returns a ready to use instance of SafeMap.
+func NewSafeMap() *SafeMap {
+	s := &SafeMap{}
+
+	s.storage = make(map[string]interface{})
+	s.less = lessString
+
+	return s
+}
+
+// lessString orders strings in increasing order.
+func lessString(a, b string) bool {
+	return a < b
+}
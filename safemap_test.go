@@ -1,8 +1,10 @@
 package xtypes
 
 import (
+	"fmt"
 	"reflect"
 	"sort"
+	"sync"
 	"testing"
 )
 
@@ -160,3 +162,345 @@ func TestSafeMap_Drain(t *testing.T) {
 		t.Fatalf("expected %#v, got %#v", expectedValues, actualValues)
 	}
 }
+
+func TestSafeMapOf_IntKeys(t *testing.T) {
+	sm := NewSafeMapOf[int, string](func(a, b int) bool { return a < b })
+
+	sm.Set(3, "three")
+	sm.Set(1, "one")
+	sm.Set(2, "two")
+
+	v, ok := sm.Get(2)
+	if !ok {
+		t.Fatalf("key not found")
+	}
+
+	if v != "two" {
+		t.Fatalf("expected %s, got %s", "two", v)
+	}
+
+	expectedKeys := []int{1, 2, 3}
+	if actual := sm.Keys(); !reflect.DeepEqual(expectedKeys, actual) {
+		t.Fatalf("expected %#v, got %#v", expectedKeys, actual)
+	}
+
+	expectedValues := []string{"one", "two", "three"}
+	if actual := sm.Drain(); !reflect.DeepEqual(expectedValues, actual) {
+		t.Fatalf("expected %#v, got %#v", expectedValues, actual)
+	}
+
+	if l := sm.Len(); l != 0 {
+		t.Fatalf("expected %d, got %d", 0, l)
+	}
+}
+
+func TestSafeMapOf_StructKeys(t *testing.T) {
+	type key struct {
+		tenant string
+		id     [4]byte
+	}
+
+	sm := NewSafeMapOf[key, int](nil)
+
+	k1 := key{tenant: "a", id: [4]byte{1, 2, 3, 4}}
+	k2 := key{tenant: "b", id: [4]byte{1, 2, 3, 4}}
+
+	sm.Set(k1, 1)
+	sm.Set(k2, 2)
+
+	if v, ok := sm.Get(k1); !ok || v != 1 {
+		t.Fatalf("expected %d, got %d", 1, v)
+	}
+
+	sm.Del(k1)
+
+	if _, ok := sm.Get(k1); ok {
+		t.Fatalf("expected key to be deleted")
+	}
+
+	expected := []key{k2}
+	if actual := sm.Keys(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+}
+
+func TestSafeMapOf_Unordered(t *testing.T) {
+	sm := NewSafeMapOf[string, int](nil)
+
+	expected := []string{"a", "b", "c", "d"}
+	for i, k := range expected {
+		sm.Set(k, i)
+	}
+
+	actual := sm.Keys()
+	sort.Strings(actual)
+
+	if !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+
+	if values := sm.Drain(); len(values) != len(expected) {
+		t.Fatalf("expected %d items, got %d items", len(expected), len(values))
+	}
+}
+
+func TestSafeMapOf_ConcurrentReads(t *testing.T) {
+	sm := NewSafeMapOf[int, int](nil)
+
+	for i := 0; i < 100; i++ {
+		sm.Set(i, i)
+	}
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for j := 0; j < 1000; j++ {
+				if w == 0 {
+					sm.Set(j%100, j%100)
+					continue
+				}
+
+				if v, ok := sm.Get(j % 100); !ok || v != j%100 {
+					t.Errorf("expected %d, got %d", j%100, v)
+					return
+				}
+
+				sm.Len()
+				sm.Keys()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+}
+
+func TestSafeMapOf_GetOrSet(t *testing.T) {
+	sm := NewSafeMapOf[string, int](nil)
+
+	if v, loaded := sm.GetOrSet("a", 1); loaded || v != 1 {
+		t.Fatalf("expected %d not loaded, got %d loaded %v", 1, v, loaded)
+	}
+
+	if v, loaded := sm.GetOrSet("a", 2); !loaded || v != 1 {
+		t.Fatalf("expected %d loaded, got %d loaded %v", 1, v, loaded)
+	}
+}
+
+func TestSafeMapOf_CompareAndSwap(t *testing.T) {
+	sm := NewSafeMapOf[string, int](nil)
+
+	if sm.CompareAndSwap("a", 0, 1) {
+		t.Fatal("expected missing key not to be swapped")
+	}
+
+	sm.Set("a", 1)
+
+	if sm.CompareAndSwap("a", 2, 3) {
+		t.Fatal("expected not to swap")
+	}
+
+	if !sm.CompareAndSwap("a", 1, 3) {
+		t.Fatal("expected to swap")
+	}
+
+	if v, _ := sm.Get("a"); v != 3 {
+		t.Fatalf("expected %d, got %d", 3, v)
+	}
+
+	if sm.CompareAndDelete("a", 1) {
+		t.Fatal("expected not to delete")
+	}
+
+	if !sm.CompareAndDelete("a", 3) {
+		t.Fatal("expected to delete")
+	}
+
+	if l := sm.Len(); l != 0 {
+		t.Fatalf("expected %d, got %d", 0, l)
+	}
+}
+
+func TestSafeMapOf_CompareAndSwapIncomparable(t *testing.T) {
+	sm := NewSafeMapOf[string, []int](nil)
+
+	sm.Set("a", []int{1})
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected panic")
+		}
+	}()
+
+	sm.CompareAndSwap("a", []int{1}, []int{2})
+}
+
+func TestSafeMapOf_Update(t *testing.T) {
+	sm := NewSafeMapOf[string, int](nil)
+
+	incr := func(old int, ok bool) (int, bool) {
+		return old + 1, true
+	}
+
+	sm.Update("a", incr)
+
+	if v, ok := sm.Update("a", incr); !ok || v != 2 {
+		t.Fatalf("expected %d, got %d", 2, v)
+	}
+
+	v, ok := sm.Update("a", func(old int, ok bool) (int, bool) {
+		return old, false
+	})
+
+	if ok || v != 2 {
+		t.Fatalf("expected %d deleted, got %d kept %v", 2, v, ok)
+	}
+
+	if l := sm.Len(); l != 0 {
+		t.Fatalf("expected %d, got %d", 0, l)
+	}
+}
+
+func TestSafeMapOf_GetAndDelete(t *testing.T) {
+	sm := NewSafeMapOf[string, int](nil)
+
+	if _, ok := sm.GetAndDelete("a"); ok {
+		t.Fatal("expected key not found")
+	}
+
+	sm.Set("a", 1)
+
+	if v, ok := sm.GetAndDelete("a"); !ok || v != 1 {
+		t.Fatalf("expected %d, got %d", 1, v)
+	}
+
+	if _, ok := sm.Get("a"); ok {
+		t.Fatal("expected key to be deleted")
+	}
+}
+
+func TestSafeMapOf_AtomicRace(t *testing.T) {
+	const (
+		workers = 8
+		rounds  = 1000
+	)
+
+	sm := NewSafeMapOf[string, int](nil)
+	tokens := NewSafeMapOf[int, int](nil)
+
+	for i := 0; i < rounds; i++ {
+		tokens.Set(i, i)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+		popped  int
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < rounds; j++ {
+				sm.Update("counter", func(old int, ok bool) (int, bool) {
+					return old + 1, true
+				})
+
+				// Only one of the workers creates the value.
+				if _, loaded := sm.GetOrSet("once", j); !loaded {
+					mu.Lock()
+					created++
+					mu.Unlock()
+				}
+
+				for {
+					v, _ := sm.Get("cas")
+					if sm.CompareAndSwap("cas", v, v+1) {
+						break
+					}
+
+					if _, loaded := sm.GetOrSet("cas", 1); !loaded {
+						break
+					}
+				}
+
+				// Every token is popped exactly once.
+				if _, ok := tokens.GetAndDelete(j); ok {
+					mu.Lock()
+					popped++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if v, _ := sm.Get("counter"); v != workers*rounds {
+		t.Fatalf("expected %d, got %d", workers*rounds, v)
+	}
+
+	if v, _ := sm.Get("cas"); v != workers*rounds {
+		t.Fatalf("expected %d, got %d", workers*rounds, v)
+	}
+
+	if created != 1 {
+		t.Fatalf("expected %d, got %d", 1, created)
+	}
+
+	if popped != rounds {
+		t.Fatalf("expected %d, got %d", rounds, popped)
+	}
+}
+
+// Benchmarks.
+
+// benchMap is the API shared by the maps under benchmark.
+type benchMap interface {
+	Get(key int) (int, bool)
+	Set(key int, value int) error
+}
+
+// benchMapKeys is the number of keys in benchmarked maps.
+const benchMapKeys = 1024
+
+func BenchmarkSafeMapOf_ReadWrite(b *testing.B) {
+	benchmarkReadWrite(b, func() benchMap { return NewSafeMapOf[int, int](nil) })
+}
+
+func BenchmarkSnapshotMapOf_ReadWrite(b *testing.B) {
+	benchmarkReadWrite(b, func() benchMap { return NewSnapshotMapOf[int, int](nil) })
+}
+
+// benchmarkReadWrite runs parallel reads and writes on maps created by fn at various write ratios.
+func benchmarkReadWrite(b *testing.B, fn func() benchMap) {
+	for _, writes := range []int{50, 10, 1, 0} {
+		b.Run(fmt.Sprintf("writes=%d%%", writes), func(b *testing.B) {
+			m := fn()
+
+			for i := 0; i < benchMapKeys; i++ {
+				m.Set(i, i)
+			}
+
+			b.ResetTimer()
+
+			b.RunParallel(func(pb *testing.PB) {
+				for i := 0; pb.Next(); i++ {
+					if i%100 < writes {
+						m.Set(i%benchMapKeys, i)
+					} else {
+						m.Get(i % benchMapKeys)
+					}
+				}
+			})
+		})
+	}
+}
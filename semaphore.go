@@ -1,8 +1,22 @@
 package xtypes
 
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
 // Semaphore presents a channel that implements the Semaphore pattern.
+//
+// In the debug mode the semaphore records which goroutines hold resources, see SetDebug.
 type Semaphore chan struct{}
 
+var (
+	semaphoreDebugMu sync.Mutex                     // Protects semaphoreDebug.
+	semaphoreDebug   map[Semaphore]semaphoreHolders // Holders of semaphores in the debug mode.
+	semaphoreDebugOn atomic.Int32                   // Number of semaphores in the debug mode, zero skips the lock.
+)
+
 // NewSemaphore returns a new semaphore ready to use.
 func NewSemaphore(size int) Semaphore {
 	return make(Semaphore, size)
@@ -14,15 +28,139 @@ func (s Semaphore) Acquire(n int) {
 	for i := 0; i < n; i++ {
 		s <- l
 	}
+
+	s.hold(n)
+}
+
+// AcquireCtx gets the n resources from the Semaphore, blocking until they are available or ctx is done.
+//
+// On cancellation the resources taken so far are given back and ctx.Err() is returned.
+func (s Semaphore) AcquireCtx(ctx context.Context, n int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	l := struct{}{}
+	for i := 0; i < n; i++ {
+		select {
+		case s <- l:
+		case <-ctx.Done():
+			s.release(i)
+
+			return ctx.Err()
+		}
+	}
+
+	s.hold(n)
+
+	return nil
+}
+
+// TryAcquire gets the n resources from the Semaphore without blocking.
+//
+// It returns false and takes nothing if the n resources are not available right away.
+func (s Semaphore) TryAcquire(n int) bool {
+	l := struct{}{}
+	for i := 0; i < n; i++ {
+		select {
+		case s <- l:
+		default:
+			s.release(i)
+
+			return false
+		}
+	}
+
+	s.hold(n)
+
+	return true
 }
 
 // Release returns the specified number of resources to the Semaphore.
-func (s Semaphore) Release(n int) {
-	if len(s) == 0 {
-		return
+//
+// It never blocks. If fewer than n resources are held, nothing is released and ErrOverRelease is returned,
+// as WeightedSemaphore does. Concurrent calls which release more than held together may release part of n.
+func (s Semaphore) Release(n int) error {
+	if n > len(s) {
+		return ErrOverRelease
 	}
 
+	for i := 0; i < n; i++ {
+		select {
+		case <-s:
+		default:
+			return ErrOverRelease
+		}
+	}
+
+	s.unhold(n)
+
+	return nil
+}
+
+// SetDebug switches the debug mode of the semaphore.
+//
+// In the debug mode every acquisition records the calling goroutine, so leaked resources can be found with Holders.
+// Resources held when the mode is switched on are not attributed to any goroutine.
+// The records are kept in a table of the package, so the mode MUST be switched off when the semaphore is no longer used.
+func (s Semaphore) SetDebug(enabled bool) {
+	semaphoreDebugMu.Lock()
+	defer semaphoreDebugMu.Unlock()
+
+	_, ok := semaphoreDebug[s]
+
+	switch {
+	case !enabled && ok:
+		delete(semaphoreDebug, s)
+		semaphoreDebugOn.Add(-1)
+	case enabled && !ok:
+		if semaphoreDebug == nil {
+			semaphoreDebug = make(map[Semaphore]semaphoreHolders)
+		}
+
+		semaphoreDebug[s] = make(semaphoreHolders)
+		semaphoreDebugOn.Add(1)
+	}
+}
+
+// Holders returns the goroutines holding resources ordered by goroutine ID.
+//
+// It returns nil unless the semaphore is in the debug mode.
+// Resources released by a goroutine which holds none are taken off the other holders in the order of their IDs.
+func (s Semaphore) Holders() []SemaphoreHolder {
+	semaphoreDebugMu.Lock()
+	defer semaphoreDebugMu.Unlock()
+
+	return semaphoreDebug[s].list()
+}
+
+// release returns n resources which are known to be held.
+func (s Semaphore) release(n int) {
 	for i := 0; i < n; i++ {
 		<-s
 	}
 }
+
+// hold records n resources acquired by the calling goroutine in the debug mode.
+func (s Semaphore) hold(n int) {
+	if semaphoreDebugOn.Load() == 0 {
+		return
+	}
+
+	semaphoreDebugMu.Lock()
+	defer semaphoreDebugMu.Unlock()
+
+	semaphoreDebug[s].hold(n)
+}
+
+// unhold takes n released resources off the holders in the debug mode.
+func (s Semaphore) unhold(n int) {
+	if semaphoreDebugOn.Load() == 0 {
+		return
+	}
+
+	semaphoreDebugMu.Lock()
+	defer semaphoreDebugMu.Unlock()
+
+	semaphoreDebug[s].unhold(n)
+}
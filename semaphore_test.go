@@ -1,7 +1,10 @@
 package xtypes
 
 import (
+	"context"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestNewSemaphore(t *testing.T) {
@@ -45,10 +48,155 @@ func TestSemaphore_ReleaseEmpty(t *testing.T) {
 	sema.Acquire(1)
 	sema.Release(1)
 
-	sema.Release(1)
+	if err := sema.Release(1); err != ErrOverRelease {
+		t.Fatalf("expected %v, got %v", ErrOverRelease, err)
+	}
 
 	expected := 0
 	if v := len(sema); v != expected {
 		t.Fatalf("expected %d type, got %d", expected, v)
 	}
 }
+
+func TestSemaphore_AcquireCtx(t *testing.T) {
+	sema := NewSemaphore(2)
+
+	if err := sema.AcquireCtx(context.Background(), 2); err != nil {
+		t.Fatalf("failed to acquire: %v", err)
+	}
+
+	expected := 2
+	if v := len(sema); v != expected {
+		t.Fatalf("expected %d, got %d", expected, v)
+	}
+}
+
+func TestSemaphore_AcquireCtxPartial(t *testing.T) {
+	sema := NewSemaphore(3)
+
+	sema.Acquire(1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	// Two resources are taken before the deadline, they must be given back.
+	if err := sema.AcquireCtx(ctx, 3); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	expected := 1
+	if v := len(sema); v != expected {
+		t.Fatalf("expected %d, got %d", expected, v)
+	}
+}
+
+func TestSemaphore_AcquireCtxCancelled(t *testing.T) {
+	sema := NewSemaphore(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if err := sema.AcquireCtx(ctx, 1); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	expected := 0
+	if v := len(sema); v != expected {
+		t.Fatalf("expected %d, got %d", expected, v)
+	}
+}
+
+func TestSemaphore_TryAcquire(t *testing.T) {
+	sema := NewSemaphore(2)
+
+	if !sema.TryAcquire(1) {
+		t.Fatal("expected to acquire")
+	}
+
+	if sema.TryAcquire(2) {
+		t.Fatal("expected not to acquire")
+	}
+
+	expected := 1
+	if v := len(sema); v != expected {
+		t.Fatalf("expected %d, got %d", expected, v)
+	}
+
+	if sema.TryAcquire(3) {
+		t.Fatal("expected not to acquire more than capacity")
+	}
+
+	if !sema.TryAcquire(1) {
+		t.Fatal("expected to acquire")
+	}
+}
+
+func TestSemaphore_ReleaseMore(t *testing.T) {
+	sema := NewSemaphore(3)
+
+	sema.Acquire(2)
+
+	// Used to block forever.
+	if err := sema.Release(3); err != ErrOverRelease {
+		t.Fatalf("expected %v, got %v", ErrOverRelease, err)
+	}
+
+	// Nothing is released.
+	expected := 2
+	if v := len(sema); v != expected {
+		t.Fatalf("expected %d, got %d", expected, v)
+	}
+}
+
+func TestSemaphore_Debug(t *testing.T) {
+	sema := NewSemaphore(4)
+
+	if h := sema.Holders(); h != nil {
+		t.Fatalf("expected nil, got %v", h)
+	}
+
+	sema.SetDebug(true)
+	defer sema.SetDebug(false)
+
+	sema.Acquire(1)
+	sema.TryAcquire(1)
+
+	holders := sema.Holders()
+	if len(holders) != 1 || holders[0].Count != 2 {
+		t.Fatalf("expected a holder of %d, got %v", 2, holders)
+	}
+
+	if !strings.Contains(holders[0].Stack, "TestSemaphore_Debug") {
+		t.Fatalf("expected stack of the test, got %s", holders[0].Stack)
+	}
+
+	// A leaked acquisition in another goroutine.
+	done := make(chan struct{})
+
+	go func() {
+		sema.AcquireCtx(context.Background(), 1)
+		close(done)
+	}()
+
+	<-done
+
+	if holders = sema.Holders(); len(holders) != 2 {
+		t.Fatalf("expected %d holders, got %d", 2, len(holders))
+	}
+
+	// Failed acquisitions and over-releases are not recorded.
+	sema.TryAcquire(2)
+	sema.Release(4)
+
+	sema.Release(2)
+
+	if holders = sema.Holders(); len(holders) != 1 || holders[0].Count != 1 {
+		t.Fatalf("expected the leaked holder, got %v", holders)
+	}
+
+	sema.SetDebug(false)
+
+	if h := sema.Holders(); h != nil {
+		t.Fatalf("expected nil, got %v", h)
+	}
+}
@@ -0,0 +1,217 @@
+package xtypes
+
+import (
+	"sort"
+	"unsafe"
+)
+
+// cacheLineSize is the size of a CPU cache line assumed for padding.
+const cacheLineSize = 64
+
+// shardPad is the padding which rounds the size of a shard up to a multiple of cacheLineSize.
+// The size of SafeMapOf does not depend on its type parameters.
+const shardPad = (cacheLineSize - unsafe.Sizeof(SafeMapOf[struct{}, struct{}]{})%cacheLineSize) % cacheLineSize
+
+// ShardedMapOf is a map split into independently locked shards to reduce contention between writers.
+//
+// Keys are spread across shards by the hash function.
+// Shards are padded to cache lines, so writers of neighbouring shards do not invalidate each other's caches.
+// Get, Set and Del lock only the shard of the key, Keys and Drain lock all shards at once,
+// Len sums shards one by one and is approximate under concurrent writes.
+// Keys and Drain return data sorted by key if the map has been created with a key ordering,
+// otherwise the order is unspecified.
+// ShardedMapOf MUST be created using constructor.
+type ShardedMapOf[K comparable, V any] struct {
+	shards []mapShard[K, V]
+	mask   uint64
+	hash   func(key K) uint64
+	less   func(a, b K) bool
+}
+
+// mapShard is a shard of ShardedMapOf padded to a multiple of the cache line size.
+type mapShard[K comparable, V any] struct {
+	SafeMapOf[K, V]
+	_ [shardPad]byte
+}
+
+// NewShardedMapOf returns a ready to use instance of ShardedMapOf.
+//
+// The number of shards is rounded up to a power of two.
+// The less function defines the order of keys for Keys and Drain, it can be nil.
+func NewShardedMapOf[K comparable, V any](shards int, hash func(key K) uint64, less func(a, b K) bool) *ShardedMapOf[K, V] {
+	n := 1
+	for n < shards {
+		n <<= 1
+	}
+
+	s := &ShardedMapOf[K, V]{
+		shards: make([]mapShard[K, V], n),
+		mask:   uint64(n - 1),
+		hash:   hash,
+		less:   less,
+	}
+
+	for i := range s.shards {
+		s.shards[i].storage = make(map[K]V)
+	}
+
+	return s
+}
+
+// Get returns object.
+func (s *ShardedMapOf[K, V]) Get(key K) (V, bool) {
+	return s.shard(key).Get(key)
+}
+
+// Set sets the object.
+func (s *ShardedMapOf[K, V]) Set(key K, value V) error {
+	return s.shard(key).Set(key, value)
+}
+
+// Del deletes the object.
+func (s *ShardedMapOf[K, V]) Del(key K) {
+	s.shard(key).Del(key)
+}
+
+// GetOrSet returns the existing value for the key if present, loaded is true then.
+// Otherwise it sets and returns the given value.
+func (s *ShardedMapOf[K, V]) GetOrSet(key K, value V) (actual V, loaded bool) {
+	return s.shard(key).GetOrSet(key, value)
+}
+
+// CompareAndSwap sets the new value for the key if its current value is equal to old.
+//
+// Values are compared with ==, it panics if V is not comparable, e.g. a slice or a map.
+func (s *ShardedMapOf[K, V]) CompareAndSwap(key K, old, new V) bool {
+	return s.shard(key).CompareAndSwap(key, old, new)
+}
+
+// CompareAndDelete deletes the key if its current value is equal to old.
+//
+// Values are compared with ==, it panics if V is not comparable, e.g. a slice or a map.
+func (s *ShardedMapOf[K, V]) CompareAndDelete(key K, old V) bool {
+	return s.shard(key).CompareAndDelete(key, old)
+}
+
+// Update replaces the value of the key with the result of fn, see SafeMapOf.Update.
+func (s *ShardedMapOf[K, V]) Update(key K, fn func(old V, ok bool) (new V, keep bool)) (V, bool) {
+	return s.shard(key).Update(key, fn)
+}
+
+// GetAndDelete deletes the key and returns its previous value if present.
+func (s *ShardedMapOf[K, V]) GetAndDelete(key K) (V, bool) {
+	return s.shard(key).GetAndDelete(key)
+}
+
+// Drain returns all elements as slice and removes keys from the storage.
+// Elements are sorted by key across all shards if the key ordering is set.
+func (s *ShardedMapOf[K, V]) Drain() []V {
+	s.lock()
+	defer s.unlock()
+
+	keys := s.keys()
+	data := make([]V, 0, len(keys))
+
+	for _, k := range keys {
+		sh := s.shard(k)
+
+		data = append(data, sh.storage[k])
+		delete(sh.storage, k)
+	}
+
+	return data
+}
+
+// Len returns count of elements in the storage.
+func (s *ShardedMapOf[K, V]) Len() int {
+	n := 0
+
+	for i := range s.shards {
+		n += s.shards[i].Len()
+	}
+
+	return n
+}
+
+// Keys returns all the keys as a slice.
+// Keys are sorted across all shards if the key ordering is set.
+func (s *ShardedMapOf[K, V]) Keys() []K {
+	s.lock()
+	defer s.unlock()
+
+	return s.keys()
+}
+
+// shard returns the shard of the key.
+func (s *ShardedMapOf[K, V]) shard(key K) *SafeMapOf[K, V] {
+	return &s.shards[s.hash(key)&s.mask].SafeMapOf
+}
+
+// lock locks all shards in order.
+func (s *ShardedMapOf[K, V]) lock() {
+	for i := range s.shards {
+		s.shards[i].mu.Lock()
+	}
+}
+
+// unlock unlocks all shards.
+func (s *ShardedMapOf[K, V]) unlock() {
+	for i := range s.shards {
+		s.shards[i].mu.Unlock()
+	}
+}
+
+// keys returns a slice of keys of all shards.
+func (s *ShardedMapOf[K, V]) keys() []K {
+	n := 0
+	for i := range s.shards {
+		n += len(s.shards[i].storage)
+	}
+
+	keys := make([]K, 0, n)
+
+	for i := range s.shards {
+		for k := range s.shards[i].storage {
+			keys = append(keys, k)
+		}
+	}
+
+	if s.less != nil {
+		sort.Slice(keys, func(i, j int) bool {
+			return s.less(keys[i], keys[j])
+		})
+	}
+
+	return keys
+}
+
+// ShardedMap provides a sharded storage with string keys.
+//
+// Keys are hashed with FNV-1a. Keys and Drain return data sorted by key.
+type ShardedMap struct {
+	ShardedMapOf[string, interface{}]
+}
+
+// NewShardedMap returns a ready to use instance of ShardedMap with the given number of shards.
+func NewShardedMap(shards int) *ShardedMap {
+	return &ShardedMap{
+		ShardedMapOf: *NewShardedMapOf[string, interface{}](shards, hashString, lessString),
+	}
+}
+
+// hashString returns the 64-bit FNV-1a hash of the string.
+func hashString(s string) uint64 {
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+
+	h := uint64(offset)
+
+	for i := 0; i < len(s); i++ {
+		h ^= uint64(s[i])
+		h *= prime
+	}
+
+	return h
+}
@@ -0,0 +1,268 @@
+package xtypes
+
+import (
+	"fmt"
+	"hash/fnv"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+	"unsafe"
+)
+
+func TestNewShardedMap(t *testing.T) {
+	sm := NewShardedMap(3)
+	if sm == nil {
+		t.Fatalf("failed to create map")
+	}
+
+	if l := len(sm.shards); l != 4 {
+		t.Fatalf("expected %d shards, got %d", 4, l)
+	}
+}
+
+func TestShardedMap(t *testing.T) {
+	sm := NewShardedMap(8)
+
+	expectedKeys := make([]string, 0, 100)
+	expectedValues := make([]interface{}, 0, 100)
+
+	for i := 0; i < 100; i++ {
+		k := fmt.Sprintf("key%03d", i)
+
+		expectedKeys = append(expectedKeys, k)
+		expectedValues = append(expectedValues, i)
+	}
+
+	// Insert in reverse, so the order does not come from insertion.
+	for i := len(expectedKeys) - 1; i >= 0; i-- {
+		sm.Set(expectedKeys[i], i)
+	}
+
+	// Keys are actually spread across shards.
+	for i := range sm.shards {
+		if sm.shards[i].Len() == 0 {
+			t.Fatalf("expected shard %d to have keys", i)
+		}
+	}
+
+	if v, ok := sm.Get("key042"); !ok || v != 42 {
+		t.Fatalf("expected %d, got %v", 42, v)
+	}
+
+	sm.Del("key099")
+
+	if _, ok := sm.Get("key099"); ok {
+		t.Fatalf("expected key to be deleted")
+	}
+
+	expectedKeys = expectedKeys[:99]
+	expectedValues = expectedValues[:99]
+
+	if l := sm.Len(); l != 99 {
+		t.Fatalf("expected %d, got %d", 99, l)
+	}
+
+	if actual := sm.Keys(); !reflect.DeepEqual(expectedKeys, actual) {
+		t.Fatalf("expected %#v, got %#v", expectedKeys, actual)
+	}
+
+	if actual := sm.Drain(); !reflect.DeepEqual(expectedValues, actual) {
+		t.Fatalf("expected %#v, got %#v", expectedValues, actual)
+	}
+
+	if l := sm.Len(); l != 0 {
+		t.Fatalf("expected %d, got %d", 0, l)
+	}
+}
+
+func TestShardedMapOf_ShardSize(t *testing.T) {
+	if size := unsafe.Sizeof(mapShard[string, int]{}); size%cacheLineSize != 0 {
+		t.Fatalf("expected a multiple of %d, got %d", cacheLineSize, size)
+	}
+}
+
+func TestShardedMapOf_Concurrent(t *testing.T) {
+	sm := NewShardedMapOf[int, int](16, func(k int) uint64 { return uint64(k) }, nil)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for j := 0; j < 1000; j++ {
+				k := w*1000 + j
+
+				sm.Set(k, k)
+
+				if v, ok := sm.Get(k); !ok || v != k {
+					t.Errorf("expected %d, got %d", k, v)
+					return
+				}
+
+				if j%100 == 0 {
+					sm.Keys()
+				}
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if l := sm.Len(); l != 8000 {
+		t.Fatalf("expected %d, got %d", 8000, l)
+	}
+}
+
+func TestShardedMapOf_AtomicRace(t *testing.T) {
+	const (
+		workers = 8
+		rounds  = 512
+		keys    = 8
+	)
+
+	// Consecutive keys go to different shards.
+	sm := NewShardedMapOf[int, int](keys, func(key int) uint64 { return uint64(key) }, nil)
+
+	for j := 0; j < rounds; j++ {
+		sm.Set(2000+j, j)
+		sm.Set(3000+j, j)
+	}
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+		popped  int
+		deleted int
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < rounds; j++ {
+				k := j % keys
+
+				sm.Update(k, func(old int, ok bool) (int, bool) {
+					return old + 1, true
+				})
+
+				// Only one of the workers creates the value of each key.
+				if _, loaded := sm.GetOrSet(100+k, j); !loaded {
+					mu.Lock()
+					created++
+					mu.Unlock()
+				}
+
+				for {
+					v, _ := sm.Get(1000 + k)
+					if sm.CompareAndSwap(1000+k, v, v+1) {
+						break
+					}
+
+					if _, loaded := sm.GetOrSet(1000+k, 1); !loaded {
+						break
+					}
+				}
+
+				// Every token is taken exactly once.
+				if _, ok := sm.GetAndDelete(2000 + j); ok {
+					mu.Lock()
+					popped++
+					mu.Unlock()
+				}
+
+				if sm.CompareAndDelete(3000+j, j) {
+					mu.Lock()
+					deleted++
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	expected := workers * rounds / keys
+
+	for k := 0; k < keys; k++ {
+		if v, _ := sm.Get(k); v != expected {
+			t.Fatalf("expected %d, got %d", expected, v)
+		}
+
+		if v, _ := sm.Get(1000 + k); v != expected {
+			t.Fatalf("expected %d, got %d", expected, v)
+		}
+	}
+
+	if created != keys {
+		t.Fatalf("expected %d, got %d", keys, created)
+	}
+
+	if popped != rounds || deleted != rounds {
+		t.Fatalf("expected %d, got %d and %d", rounds, popped, deleted)
+	}
+
+	if l := sm.Len(); l != 3*keys {
+		t.Fatalf("expected %d, got %d", 3*keys, l)
+	}
+}
+
+func TestHashString(t *testing.T) {
+	for _, s := range []string{"", "a", "Hello, World"} {
+		h := fnv.New64a()
+		h.Write([]byte(s))
+
+		if expected, actual := h.Sum64(), hashString(s); expected != actual {
+			t.Fatalf("expected %d, got %d", expected, actual)
+		}
+	}
+}
+
+// Benchmarks.
+
+func BenchmarkSafeMap_Contention(b *testing.B) {
+	sm := NewSafeMap()
+
+	benchmarkContention(b, sm.Set)
+}
+
+func BenchmarkShardedMap_Contention(b *testing.B) {
+	sm := NewShardedMap(64)
+
+	benchmarkContention(b, sm.Set)
+}
+
+// benchmarkContention runs writes through set from 1, 8 and 64 goroutines.
+func benchmarkContention(b *testing.B, set func(key string, value interface{}) error) {
+	keys := make([]string, benchMapKeys)
+	for i := range keys {
+		keys[i] = strconv.Itoa(i)
+	}
+
+	for _, g := range []int{1, 8, 64} {
+		b.Run(fmt.Sprintf("goroutines=%d", g), func(b *testing.B) {
+			var wg sync.WaitGroup
+
+			for i := 0; i < g; i++ {
+				wg.Add(1)
+
+				go func(w int) {
+					defer wg.Done()
+
+					for j := w; j < b.N; j += g {
+						set(keys[j%len(keys)], j)
+					}
+				}(i)
+			}
+
+			wg.Wait()
+		})
+	}
+}
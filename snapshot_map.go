@@ -0,0 +1,132 @@
+package xtypes
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// SnapshotMapOf is a copy-on-write map for data which is mostly read.
+//
+// Reads load an immutable snapshot atomically and never take a lock.
+// Writes are serialised by the mutex and replace the snapshot with a modified copy,
+// so each of them costs O(n). Use SafeMapOf when writes are frequent.
+// Keys and Drain return data sorted by key if the map has been created with a key ordering,
+// otherwise the order is unspecified.
+// SnapshotMapOf MUST be created using constructor.
+type SnapshotMapOf[K comparable, V any] struct {
+	mu      sync.Mutex // Serialises writers.
+	storage atomic.Pointer[map[K]V]
+	less    func(a, b K) bool
+}
+
+// NewSnapshotMapOf returns a ready to use instance of SnapshotMapOf.
+//
+// The less function defines the order of keys for Keys and Drain, it can be nil.
+func NewSnapshotMapOf[K comparable, V any](less func(a, b K) bool) *SnapshotMapOf[K, V] {
+	s := &SnapshotMapOf[K, V]{
+		less: less,
+	}
+
+	s.storage.Store(&map[K]V{})
+
+	return s
+}
+
+// Get returns object.
+func (s *SnapshotMapOf[K, V]) Get(key K) (V, bool) {
+	r, ok := s.load()[key]
+
+	return r, ok
+}
+
+// Set sets the object.
+func (s *SnapshotMapOf[K, V]) Set(key K, value V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.clone(1)
+	data[key] = value
+
+	s.storage.Store(&data)
+
+	return nil
+}
+
+// Del deletes the object.
+func (s *SnapshotMapOf[K, V]) Del(key K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.load()[key]; !ok {
+		return
+	}
+
+	data := s.clone(0)
+	delete(data, key)
+
+	s.storage.Store(&data)
+}
+
+// Drain returns all elements as slice and removes keys from the storage.
+// Elements are sorted by key if the key ordering is set.
+func (s *SnapshotMapOf[K, V]) Drain() []V {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data := s.load()
+	s.storage.Store(&map[K]V{})
+
+	result := make([]V, 0, len(data))
+
+	for _, k := range s.keys(data) {
+		result = append(result, data[k])
+	}
+
+	return result
+}
+
+// Len returns count of elements in the storage.
+func (s *SnapshotMapOf[K, V]) Len() int {
+	return len(s.load())
+}
+
+// Keys returns all the keys as a slice.
+// Keys are sorted if the key ordering is set.
+func (s *SnapshotMapOf[K, V]) Keys() []K {
+	return s.keys(s.load())
+}
+
+// load returns the current snapshot. It MUST NOT be modified.
+func (s *SnapshotMapOf[K, V]) load() map[K]V {
+	return *s.storage.Load()
+}
+
+// clone returns a copy of the current snapshot with room for extra keys.
+func (s *SnapshotMapOf[K, V]) clone(extra int) map[K]V {
+	data := s.load()
+	result := make(map[K]V, len(data)+extra)
+
+	for k, v := range data {
+		result[k] = v
+	}
+
+	return result
+}
+
+// keys returns a slice of keys of the snapshot.
+func (s *SnapshotMapOf[K, V]) keys(data map[K]V) []K {
+	keys := make([]K, 0, len(data))
+
+	for k := range data {
+		keys = append(keys, k)
+	}
+
+	if s.less != nil {
+		sort.Slice(keys, func(i, j int) bool {
+			return s.less(keys[i], keys[j])
+		})
+	}
+
+	return keys
+}
@@ -0,0 +1,88 @@
+package xtypes
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestNewSnapshotMapOf(t *testing.T) {
+	sm := NewSnapshotMapOf[string, int](nil)
+	if sm == nil {
+		t.Fatalf("failed to create map")
+	}
+}
+
+func TestSnapshotMapOf(t *testing.T) {
+	sm := NewSnapshotMapOf[int, string](func(a, b int) bool { return a < b })
+
+	sm.Set(3, "three")
+	sm.Set(1, "one")
+	sm.Set(2, "two")
+	sm.Set(4, "four")
+
+	v, ok := sm.Get(2)
+	if !ok {
+		t.Fatalf("key not found")
+	}
+
+	if v != "two" {
+		t.Fatalf("expected %s, got %s", "two", v)
+	}
+
+	sm.Del(4)
+	sm.Del(5)
+
+	if l := sm.Len(); l != 3 {
+		t.Fatalf("expected %d, got %d", 3, l)
+	}
+
+	expectedKeys := []int{1, 2, 3}
+	if actual := sm.Keys(); !reflect.DeepEqual(expectedKeys, actual) {
+		t.Fatalf("expected %#v, got %#v", expectedKeys, actual)
+	}
+
+	expectedValues := []string{"one", "two", "three"}
+	if actual := sm.Drain(); !reflect.DeepEqual(expectedValues, actual) {
+		t.Fatalf("expected %#v, got %#v", expectedValues, actual)
+	}
+
+	if l := sm.Len(); l != 0 {
+		t.Fatalf("expected %d, got %d", 0, l)
+	}
+}
+
+func TestSnapshotMapOf_Concurrent(t *testing.T) {
+	sm := NewSnapshotMapOf[int, int](nil)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func(w int) {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				if w < 2 {
+					sm.Set(w*100+j, j)
+					continue
+				}
+
+				// A value is never seen half-written.
+				if v, ok := sm.Get(j); ok && v != j {
+					t.Errorf("expected %d, got %d", j, v)
+					return
+				}
+
+				sm.Keys()
+			}
+		}(i)
+	}
+
+	wg.Wait()
+
+	if l := sm.Len(); l != 200 {
+		t.Fatalf("expected %d, got %d", 200, l)
+	}
+}
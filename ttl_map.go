@@ -0,0 +1,231 @@
+package xtypes
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// TTLMapOf is a map whose entries expire after a time to live.
+//
+// Expired entries are invisible to all methods and are removed lazily when they are accessed,
+// an optional janitor started with StartJanitor removes them in the background until Close is called.
+// Time comes from the clock of the map.
+// Keys and Drain return data sorted by key if the map has been created with a key ordering,
+// otherwise the order is unspecified.
+// TTLMapOf MUST be created using constructor.
+type TTLMapOf[K comparable, V any] struct {
+	mu      sync.Mutex // Protects fields below.
+	storage map[K]ttlEntry[V]
+	less    func(a, b K) bool
+	ttl     time.Duration
+	clock   Clock
+	done    chan struct{} // Closed to stop the janitor, nil until it is started.
+	closed  bool
+}
+
+// ttlEntry is a value with its expiration time, zero if it never expires.
+type ttlEntry[V any] struct {
+	value   V
+	expires time.Time
+}
+
+// NewTTLMapOf returns a ready to use instance of TTLMapOf.
+//
+// Entries added with Set live for ttl, a non-positive ttl means they never expire.
+// If clock is nil, the system clock is used.
+// The less function defines the order of keys for Keys and Drain, it can be nil.
+func NewTTLMapOf[K comparable, V any](ttl time.Duration, clock Clock, less func(a, b K) bool) *TTLMapOf[K, V] {
+	if clock == nil {
+		clock = SystemClock()
+	}
+
+	return &TTLMapOf[K, V]{
+		storage: make(map[K]ttlEntry[V]),
+		less:    less,
+		ttl:     ttl,
+		clock:   clock,
+	}
+}
+
+// Get returns object.
+func (s *TTLMapOf[K, V]) Get(key K) (V, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.storage[key]
+	if !ok || s.expired(e, s.clock.Now()) {
+		delete(s.storage, key)
+
+		var zero V
+
+		return zero, false
+	}
+
+	return e.value, true
+}
+
+// Set sets the object with the default time to live.
+func (s *TTLMapOf[K, V]) Set(key K, value V) error {
+	return s.SetWithTTL(key, value, s.ttl)
+}
+
+// SetWithTTL sets the object which expires after ttl, a non-positive ttl means it never expires.
+func (s *TTLMapOf[K, V]) SetWithTTL(key K, value V, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e := ttlEntry[V]{value: value}
+	if ttl > 0 {
+		e.expires = s.clock.Now().Add(ttl)
+	}
+
+	s.storage[key] = e
+
+	return nil
+}
+
+// Del deletes the object.
+func (s *TTLMapOf[K, V]) Del(key K) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.storage, key)
+}
+
+// Drain returns all live elements as slice and removes keys from the storage.
+// Elements are sorted by key if the key ordering is set.
+func (s *TTLMapOf[K, V]) Drain() []V {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := s.keys()
+	data := make([]V, 0, len(keys))
+
+	for _, k := range keys {
+		data = append(data, s.storage[k].value)
+	}
+
+	s.storage = make(map[K]ttlEntry[V])
+
+	return data
+}
+
+// Len returns count of live elements in the storage.
+func (s *TTLMapOf[K, V]) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.purge()
+
+	return len(s.storage)
+}
+
+// Keys returns all the live keys as a slice.
+// Keys are sorted if the key ordering is set.
+func (s *TTLMapOf[K, V]) Keys() []K {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.keys()
+}
+
+// StartJanitor starts a goroutine which removes expired entries every interval until Close is called.
+//
+// At most one janitor runs, subsequent calls do nothing. ErrClosed is returned for a closed map,
+// ErrInvalidInterval for a non-positive interval.
+func (s *TTLMapOf[K, V]) StartJanitor(interval time.Duration) error {
+	if interval <= 0 {
+		return ErrInvalidInterval
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	if s.done != nil {
+		return nil
+	}
+
+	s.done = make(chan struct{})
+
+	go s.janitor(interval, s.done)
+
+	return nil
+}
+
+// Close stops the janitor. The map is still usable, entries expire lazily afterwards.
+// Closing a closed map returns ErrClosed.
+func (s *TTLMapOf[K, V]) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+
+	s.closed = true
+
+	if s.done != nil {
+		close(s.done)
+	}
+
+	return nil
+}
+
+// janitor removes expired entries every interval until done is closed.
+func (s *TTLMapOf[K, V]) janitor(interval time.Duration, done <-chan struct{}) {
+	for {
+		timer := s.clock.NewTimer(interval)
+
+		select {
+		case <-timer.C():
+		case <-done:
+			timer.Stop()
+
+			return
+		}
+
+		s.mu.Lock()
+		s.purge()
+		s.mu.Unlock()
+	}
+}
+
+// purge removes expired entries.
+func (s *TTLMapOf[K, V]) purge() {
+	now := s.clock.Now()
+
+	for k, e := range s.storage {
+		if s.expired(e, now) {
+			delete(s.storage, k)
+		}
+	}
+}
+
+// expired returns true if the entry has expired by now.
+func (s *TTLMapOf[K, V]) expired(e ttlEntry[V], now time.Time) bool {
+	return !e.expires.IsZero() && !now.Before(e.expires)
+}
+
+// keys removes expired entries and returns a slice of the remaining keys.
+func (s *TTLMapOf[K, V]) keys() []K {
+	s.purge()
+
+	keys := make([]K, 0, len(s.storage))
+
+	for k := range s.storage {
+		keys = append(keys, k)
+	}
+
+	if s.less != nil {
+		sort.Slice(keys, func(i, j int) bool {
+			return s.less(keys[i], keys[j])
+		})
+	}
+
+	return keys
+}
@@ -0,0 +1,124 @@
+package xtypes
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNewTTLMapOf(t *testing.T) {
+	sm := NewTTLMapOf[string, int](time.Minute, nil, nil)
+	if sm == nil {
+		t.Fatalf("failed to create map")
+	}
+}
+
+func TestTTLMapOf_Expire(t *testing.T) {
+	clock := newFakeClock()
+	sm := NewTTLMapOf[string, int](time.Minute, clock, lessString)
+
+	sm.Set("a", 1)
+	sm.SetWithTTL("b", 2, 2*time.Minute)
+	sm.SetWithTTL("c", 3, 0)
+
+	clock.Advance(time.Minute - time.Second)
+
+	if v, ok := sm.Get("a"); !ok || v != 1 {
+		t.Fatalf("expected %d, got %d", 1, v)
+	}
+
+	clock.Advance(time.Second)
+
+	if _, ok := sm.Get("a"); ok {
+		t.Fatal("expected key to expire")
+	}
+
+	expected := []string{"b", "c"}
+	if actual := sm.Keys(); !reflect.DeepEqual(expected, actual) {
+		t.Fatalf("expected %#v, got %#v", expected, actual)
+	}
+
+	clock.Advance(time.Hour)
+
+	if l := sm.Len(); l != 1 {
+		t.Fatalf("expected %d, got %d", 1, l)
+	}
+
+	// Setting an entry again renews it.
+	sm.Set("b", 4)
+
+	if actual := sm.Drain(); !reflect.DeepEqual([]int{4, 3}, actual) {
+		t.Fatalf("expected %#v, got %#v", []int{4, 3}, actual)
+	}
+
+	if l := sm.Len(); l != 0 {
+		t.Fatalf("expected %d, got %d", 0, l)
+	}
+}
+
+func TestTTLMapOf_DrainSkipsExpired(t *testing.T) {
+	clock := newFakeClock()
+	sm := NewTTLMapOf[string, int](0, clock, lessString)
+
+	sm.SetWithTTL("a", 1, time.Second)
+	sm.Set("b", 2)
+	sm.Del("c")
+
+	clock.Advance(time.Second)
+
+	if actual := sm.Drain(); !reflect.DeepEqual([]int{2}, actual) {
+		t.Fatalf("expected %#v, got %#v", []int{2}, actual)
+	}
+}
+
+func TestTTLMapOf_Janitor(t *testing.T) {
+	clock := newFakeClock()
+	sm := NewTTLMapOf[string, int](time.Minute, clock, nil)
+
+	sm.Set("a", 1)
+	sm.SetWithTTL("b", 2, 0)
+
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if err := sm.StartJanitor(interval); err != ErrInvalidInterval {
+			t.Fatalf("expected %v, got %v", ErrInvalidInterval, err)
+		}
+	}
+
+	if err := sm.StartJanitor(time.Minute); err != nil {
+		t.Fatalf("failed to start janitor: %v", err)
+	}
+
+	// A second janitor is not started.
+	sm.StartJanitor(time.Second)
+
+	clock.WaitTimers(1)
+	clock.Advance(time.Minute)
+
+	// The next timer is created once the sweep is over.
+	clock.WaitTimers(2)
+
+	sm.mu.Lock()
+	n := len(sm.storage)
+	sm.mu.Unlock()
+
+	if n != 1 {
+		t.Fatalf("expected %d entries, got %d", 1, n)
+	}
+
+	if err := sm.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	if err := sm.Close(); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+
+	if err := sm.StartJanitor(time.Minute); err != ErrClosed {
+		t.Fatalf("expected %v, got %v", ErrClosed, err)
+	}
+
+	// The map is still usable.
+	if v, ok := sm.Get("b"); !ok || v != 2 {
+		t.Fatalf("expected %d, got %d", 2, v)
+	}
+}
@@ -0,0 +1,105 @@
+package xtypes
+
+import (
+	"container/list"
+	"context"
+	"sync"
+)
+
+// waiter is a goroutine parked in waiters.
+type waiter struct {
+	ready chan struct{}
+	woken bool
+	elem  *list.Element
+}
+
+// waiters is a FIFO list of goroutines waiting for a state change.
+//
+// The zero value is ready to use.
+// It is NOT thread safe, all methods MUST be called with the owner's mutex held.
+type waiters struct {
+	list list.List
+}
+
+// wait blocks until cond returns true or ctx is done.
+//
+// It MUST be called with mu held, mu is released while waiting and held again on return.
+// On cancellation a wake-up that has already been delivered is passed on to the next waiter.
+func (w *waiters) wait(ctx context.Context, mu sync.Locker, cond func() bool) error {
+	for !cond() {
+		wt := w.enqueue()
+
+		mu.Unlock()
+
+		select {
+		case <-wt.ready:
+			mu.Lock()
+		case <-ctx.Done():
+			mu.Lock()
+			w.leave(wt)
+
+			return ctx.Err()
+		}
+	}
+
+	return nil
+}
+
+// enqueue adds a waiter to the end of the list.
+// The caller waits on its ready channel with the owner's mutex released.
+func (w *waiters) enqueue() *waiter {
+	wt := &waiter{ready: make(chan struct{})}
+	wt.elem = w.list.PushBack(wt)
+
+	return wt
+}
+
+// remove removes a waiter which stopped waiting for a reason other than a wake-up.
+func (w *waiters) remove(wt *waiter) {
+	if !wt.woken {
+		w.list.Remove(wt.elem)
+	}
+}
+
+// leave removes a waiter which gave up waiting.
+// A wake-up that has already been delivered to it is passed on to the next waiter.
+func (w *waiters) leave(wt *waiter) {
+	if wt.woken {
+		w.signal(1)
+		return
+	}
+
+	w.remove(wt)
+}
+
+// signal wakes up to n waiters in the order they started waiting.
+func (w *waiters) signal(n int) {
+	for ; n > 0; n-- {
+		e := w.list.Front()
+		if e == nil {
+			return
+		}
+
+		w.wake(e)
+	}
+}
+
+// broadcast wakes all waiters.
+func (w *waiters) broadcast() {
+	for e := w.list.Front(); e != nil; e = w.list.Front() {
+		w.wake(e)
+	}
+}
+
+// len returns the number of waiters.
+func (w *waiters) len() int {
+	return w.list.Len()
+}
+
+// wake removes the waiter from the list and unblocks it.
+func (w *waiters) wake(e *list.Element) {
+	wt := w.list.Remove(e).(*waiter)
+	wt.woken = true
+
+	close(wt.ready)
+}
@@ -0,0 +1,78 @@
+package xtypes
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWaiters_Signal(t *testing.T) {
+	var (
+		mu sync.Mutex
+		w  waiters
+	)
+
+	ready := false
+	done := make(chan error)
+
+	mu.Lock()
+
+	go func() {
+		mu.Lock()
+		defer mu.Unlock()
+
+		done <- w.wait(context.Background(), &mu, func() bool { return ready })
+	}()
+
+	// Let the waiter park.
+	for w.len() == 0 {
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+	}
+
+	ready = true
+	w.signal(1)
+	mu.Unlock()
+
+	if err := <-done; err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+}
+
+func TestWaiters_Cancel(t *testing.T) {
+	var (
+		mu sync.Mutex
+		w  waiters
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	other := &waiter{ready: make(chan struct{})}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	w.list.PushBack(other)
+
+	if err := w.wait(ctx, &mu, func() bool { return false }); err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	// The cancelled waiter has never been woken and must have removed itself.
+	if n := w.len(); n != 1 {
+		t.Fatalf("expected %d waiters, got %d", 1, n)
+	}
+
+	w.broadcast()
+
+	if !other.woken {
+		t.Fatal("expected the other waiter to be woken")
+	}
+
+	if n := w.len(); n != 0 {
+		t.Fatalf("expected %d waiters, got %d", 0, n)
+	}
+}
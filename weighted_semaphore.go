@@ -0,0 +1,350 @@
+package xtypes
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"runtime"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+// WeightedSemaphore is a semaphore which grants requests of any weight as a whole.
+//
+// Requests are served in FIFO order, a large request is not starved by a stream of small ones,
+// and since nothing is held while waiting, concurrent requests cannot deadlock on partial acquisition.
+// The size can be changed at any time with SetLimit.
+// In the debug mode the semaphore records which goroutines hold resources, see SetDebug.
+// WeightedSemaphore MUST be created using constructor.
+type WeightedSemaphore struct {
+	mu      sync.Mutex // Protects fields below.
+	size    int
+	cur     int
+	waiters list.List        // Of *weightedWaiter.
+	holders semaphoreHolders // Nil unless in the debug mode.
+}
+
+// SemaphoreHolder describes a goroutine which holds resources of a semaphore.
+type SemaphoreHolder struct {
+	Goroutine uint64 // ID of the goroutine.
+	Count     int    // Number of resources held.
+	Stack     string // Stack trace of the goroutine at the first acquisition.
+}
+
+// semaphoreHolders records resources held by goroutines, by goroutine ID.
+//
+// A nil semaphoreHolders records nothing. It is NOT thread safe.
+type semaphoreHolders map[uint64]*SemaphoreHolder
+
+// weightedWaiter is a request blocked in Acquire.
+type weightedWaiter struct {
+	n     int
+	ready chan struct{} // Closed when the request is granted or failed.
+	err   error         // Set if the request failed.
+}
+
+// NewWeightedSemaphore returns a new semaphore with the given total weight.
+func NewWeightedSemaphore(size int) *WeightedSemaphore {
+	return &WeightedSemaphore{
+		size: size,
+	}
+}
+
+// Acquire gets the n resources, blocking until all of them are available or ctx is done.
+//
+// ErrWeightTooLarge is returned if n exceeds the size of the semaphore, ErrNegativeWeight if n is negative.
+// On cancellation nothing is held and ctx.Err() is returned.
+func (s *WeightedSemaphore) Acquire(ctx context.Context, n int) error {
+	if n < 0 {
+		return ErrNegativeWeight
+	}
+
+	done := ctx.Done()
+
+	s.mu.Lock()
+
+	select {
+	case <-done:
+		s.mu.Unlock()
+
+		return ctx.Err()
+	default:
+	}
+
+	if n > s.size {
+		s.mu.Unlock()
+
+		return ErrWeightTooLarge
+	}
+
+	if s.size-s.cur >= n && s.waiters.Len() == 0 {
+		s.cur += n
+		s.holders.hold(n)
+		s.mu.Unlock()
+
+		return nil
+	}
+
+	w := &weightedWaiter{n: n, ready: make(chan struct{})}
+	elem := s.waiters.PushBack(w)
+
+	s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		if w.err != nil {
+			return w.err
+		}
+
+		s.mu.Lock()
+		s.holders.hold(n)
+		s.mu.Unlock()
+
+		return nil
+	case <-done:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-w.ready:
+		if w.err != nil {
+			return w.err
+		}
+
+		// Granted after the cancellation, give the resources back.
+		s.cur -= n
+		s.notify()
+	default:
+		front := s.waiters.Front() == elem
+		s.waiters.Remove(elem)
+
+		// Requests queued behind this one may fit now.
+		if front && s.size > s.cur {
+			s.notify()
+		}
+	}
+
+	return ctx.Err()
+}
+
+// TryAcquire gets the n resources without blocking.
+//
+// It returns false and takes nothing if the n resources are not available right away,
+// there are requests waiting before it or n is negative.
+func (s *WeightedSemaphore) TryAcquire(n int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n < 0 || s.size-s.cur < n || s.waiters.Len() > 0 {
+		return false
+	}
+
+	s.cur += n
+	s.holders.hold(n)
+
+	return true
+}
+
+// Release returns the n resources to the semaphore.
+//
+// If more resources are released than held, nothing is released and ErrOverRelease is returned.
+// ErrNegativeWeight is returned if n is negative.
+func (s *WeightedSemaphore) Release(n int) error {
+	if n < 0 {
+		return ErrNegativeWeight
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if n > s.cur {
+		return ErrOverRelease
+	}
+
+	s.cur -= n
+	s.holders.unhold(n)
+	s.notify()
+
+	return nil
+}
+
+// SetLimit changes the size of the semaphore.
+//
+// Growing grants waiting requests which fit right away.
+// Shrinking below the number of held resources takes effect as holders release,
+// requests larger than the new size which are waiting fail with ErrWeightTooLarge.
+func (s *WeightedSemaphore) SetLimit(n int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.size = max(n, 0)
+
+	for e := s.waiters.Front(); e != nil; {
+		next := e.Next()
+
+		if w := e.Value.(*weightedWaiter); w.n > s.size {
+			s.waiters.Remove(e)
+
+			w.err = ErrWeightTooLarge
+			close(w.ready)
+		}
+
+		e = next
+	}
+
+	s.notify()
+}
+
+// Limit returns the size of the semaphore.
+func (s *WeightedSemaphore) Limit() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.size
+}
+
+// SetDebug switches the debug mode of the semaphore.
+//
+// In the debug mode every acquisition records the calling goroutine, so leaked resources can be found with Holders.
+// Resources held when the mode is switched on are not attributed to any goroutine.
+func (s *WeightedSemaphore) SetDebug(enabled bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch {
+	case !enabled:
+		s.holders = nil
+	case s.holders == nil:
+		s.holders = make(semaphoreHolders)
+	}
+}
+
+// Holders returns the goroutines holding resources ordered by goroutine ID.
+//
+// It returns nil unless the semaphore is in the debug mode.
+// Resources released by a goroutine which holds none are taken off the other holders in the order of their IDs,
+// as it happens when acquisition and release are done by different goroutines.
+func (s *WeightedSemaphore) Holders() []SemaphoreHolder {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.holders.list()
+}
+
+// notify grants waiting requests in FIFO order while they fit.
+func (s *WeightedSemaphore) notify() {
+	for {
+		e := s.waiters.Front()
+		if e == nil {
+			return
+		}
+
+		w := e.Value.(*weightedWaiter)
+
+		// Stop at the first request which does not fit, so that it is not starved by the ones behind it.
+		if s.size-s.cur < w.n {
+			return
+		}
+
+		s.cur += w.n
+		s.waiters.Remove(e)
+
+		close(w.ready)
+	}
+}
+
+// currentGoroutine returns the ID and the stack trace of the calling goroutine.
+func currentGoroutine() (uint64, string) {
+	buf := make([]byte, 4096)
+	buf = buf[:runtime.Stack(buf, false)]
+
+	// The trace starts with "goroutine N [running]:".
+	field := bytes.Fields(buf)[1]
+
+	id, _ := strconv.ParseUint(string(field), 10, 64)
+
+	return id, string(buf)
+}
+
+// hold records n resources acquired by the calling goroutine.
+func (hs semaphoreHolders) hold(n int) {
+	if hs == nil || n == 0 {
+		return
+	}
+
+	id, stack := currentGoroutine()
+
+	h, ok := hs[id]
+	if !ok {
+		h = &SemaphoreHolder{Goroutine: id, Stack: stack}
+		hs[id] = h
+	}
+
+	h.Count += n
+}
+
+// unhold takes n released resources off the calling goroutine first, then off the others in the order of their IDs.
+func (hs semaphoreHolders) unhold(n int) {
+	if hs == nil || n == 0 {
+		return
+	}
+
+	id, _ := currentGoroutine()
+
+	if h, ok := hs[id]; ok {
+		n = hs.take(h, n)
+	}
+
+	if n == 0 {
+		return
+	}
+
+	ids := make([]uint64, 0, len(hs))
+	for id := range hs {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+
+	for _, id := range ids {
+		if n = hs.take(hs[id], n); n == 0 {
+			return
+		}
+	}
+}
+
+// take takes up to n resources off the holder and returns the rest.
+func (hs semaphoreHolders) take(h *SemaphoreHolder, n int) int {
+	k := min(h.Count, n)
+
+	h.Count -= k
+	if h.Count == 0 {
+		delete(hs, h.Goroutine)
+	}
+
+	return n - k
+}
+
+// list returns copies of the holders ordered by goroutine ID, nil if nothing is recorded.
+func (hs semaphoreHolders) list() []SemaphoreHolder {
+	if hs == nil {
+		return nil
+	}
+
+	result := make([]SemaphoreHolder, 0, len(hs))
+
+	for _, h := range hs {
+		result = append(result, *h)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Goroutine < result[j].Goroutine
+	})
+
+	return result
+}
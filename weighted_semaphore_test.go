@@ -0,0 +1,372 @@
+package xtypes
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewWeightedSemaphore(t *testing.T) {
+	sema := NewWeightedSemaphore(1)
+	if sema == nil {
+		t.Fatal("failed to create semaphore")
+	}
+}
+
+func TestWeightedSemaphore_Acquire(t *testing.T) {
+	sema := NewWeightedSemaphore(3)
+
+	if err := sema.Acquire(context.Background(), 2); err != nil {
+		t.Fatalf("failed to acquire: %v", err)
+	}
+
+	if sema.TryAcquire(2) {
+		t.Fatal("expected not to acquire")
+	}
+
+	if !sema.TryAcquire(1) {
+		t.Fatal("expected to acquire")
+	}
+
+	sema.Release(3)
+
+	if !sema.TryAcquire(3) {
+		t.Fatal("expected to acquire")
+	}
+}
+
+func TestWeightedSemaphore_TooLarge(t *testing.T) {
+	sema := NewWeightedSemaphore(2)
+
+	if err := sema.Acquire(context.Background(), 3); err != ErrWeightTooLarge {
+		t.Fatalf("expected %v, got %v", ErrWeightTooLarge, err)
+	}
+
+	if sema.TryAcquire(3) {
+		t.Fatal("expected not to acquire")
+	}
+}
+
+func TestWeightedSemaphore_AcquireCancel(t *testing.T) {
+	sema := NewWeightedSemaphore(2)
+
+	sema.Acquire(context.Background(), 1)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	if err := sema.Acquire(ctx, 2); err != context.DeadlineExceeded {
+		t.Fatalf("expected %v, got %v", context.DeadlineExceeded, err)
+	}
+
+	// Nothing is held by the cancelled request.
+	if !sema.TryAcquire(1) {
+		t.Fatal("expected to acquire")
+	}
+}
+
+func TestWeightedSemaphore_CancelUnblocksQueue(t *testing.T) {
+	sema := NewWeightedSemaphore(3)
+
+	sema.Acquire(context.Background(), 2)
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	large := make(chan error)
+
+	go func() {
+		large <- sema.Acquire(ctx, 3)
+	}()
+
+	waitWeightedWaiters(sema, 1)
+
+	small := make(chan error)
+
+	go func() {
+		small <- sema.Acquire(context.Background(), 1)
+	}()
+
+	waitWeightedWaiters(sema, 2)
+
+	// The small request fits but waits behind the large one until it gives up.
+	cancel()
+
+	if err := <-large; err != context.Canceled {
+		t.Fatalf("expected %v, got %v", context.Canceled, err)
+	}
+
+	if err := <-small; err != nil {
+		t.Fatalf("failed to acquire: %v", err)
+	}
+}
+
+func TestWeightedSemaphore_FIFO(t *testing.T) {
+	sema := NewWeightedSemaphore(4)
+
+	sema.Acquire(context.Background(), 4)
+
+	order := make(chan int, 3)
+
+	// The last request fits while the second one waits, but it must not overtake it.
+	weights := []int{3, 4, 1}
+	for i, n := range weights {
+		go func(i, n int) {
+			if err := sema.Acquire(context.Background(), n); err != nil {
+				t.Errorf("failed to acquire: %v", err)
+			}
+
+			order <- i
+			sema.Release(n)
+		}(i, n)
+
+		waitWeightedWaiters(sema, i+1)
+	}
+
+	sema.Release(4)
+
+	for expected := range weights {
+		if actual := <-order; actual != expected {
+			t.Fatalf("expected %d, got %d", expected, actual)
+		}
+	}
+}
+
+func TestWeightedSemaphore_NoDeadlock(t *testing.T) {
+	const size = 10
+
+	sema := NewWeightedSemaphore(size)
+
+	var wg sync.WaitGroup
+
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := 0; j < 100; j++ {
+				if err := sema.Acquire(context.Background(), size-1); err != nil {
+					t.Errorf("failed to acquire: %v", err)
+					return
+				}
+
+				sema.Release(size - 1)
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	if !sema.TryAcquire(size) {
+		t.Fatal("expected to acquire")
+	}
+}
+
+func TestWeightedSemaphore_OverRelease(t *testing.T) {
+	sema := NewWeightedSemaphore(2)
+
+	sema.Acquire(context.Background(), 1)
+
+	if err := sema.Release(2); err != ErrOverRelease {
+		t.Fatalf("expected %v, got %v", ErrOverRelease, err)
+	}
+
+	if err := sema.Release(1); err != nil {
+		t.Fatalf("failed to release: %v", err)
+	}
+
+	if err := sema.Release(1); err != ErrOverRelease {
+		t.Fatalf("expected %v, got %v", ErrOverRelease, err)
+	}
+}
+
+func TestWeightedSemaphore_NegativeWeight(t *testing.T) {
+	sema := NewWeightedSemaphore(1)
+
+	if err := sema.Acquire(context.Background(), -5); err != ErrNegativeWeight {
+		t.Fatalf("expected %v, got %v", ErrNegativeWeight, err)
+	}
+
+	if sema.TryAcquire(-5) {
+		t.Fatal("expected not to acquire a negative weight")
+	}
+
+	if err := sema.Release(-5); err != ErrNegativeWeight {
+		t.Fatalf("expected %v, got %v", ErrNegativeWeight, err)
+	}
+
+	// The size is intact.
+	if sema.TryAcquire(2) {
+		t.Fatal("expected not to acquire more than size")
+	}
+
+	if !sema.TryAcquire(1) {
+		t.Fatal("expected to acquire")
+	}
+}
+
+func TestWeightedSemaphore_Debug(t *testing.T) {
+	sema := NewWeightedSemaphore(4)
+
+	if h := sema.Holders(); h != nil {
+		t.Fatalf("expected nil, got %v", h)
+	}
+
+	sema.SetDebug(true)
+
+	sema.Acquire(context.Background(), 1)
+	sema.TryAcquire(1)
+
+	holders := sema.Holders()
+	if len(holders) != 1 {
+		t.Fatalf("expected %d holders, got %d", 1, len(holders))
+	}
+
+	self := holders[0]
+	if self.Count != 2 {
+		t.Fatalf("expected %d, got %d", 2, self.Count)
+	}
+
+	if !strings.Contains(self.Stack, "TestWeightedSemaphore_Debug") {
+		t.Fatalf("expected stack of the test, got %s", self.Stack)
+	}
+
+	// A leaked acquisition in another goroutine.
+	done := make(chan struct{})
+
+	go func() {
+		sema.Acquire(context.Background(), 1)
+		close(done)
+	}()
+
+	<-done
+
+	holders = sema.Holders()
+	if len(holders) != 2 {
+		t.Fatalf("expected %d holders, got %d", 2, len(holders))
+	}
+
+	sema.Release(2)
+
+	holders = sema.Holders()
+	if len(holders) != 1 || holders[0].Goroutine == self.Goroutine || holders[0].Count != 1 {
+		t.Fatalf("expected the other goroutine to hold %d, got %v", 1, holders)
+	}
+
+	// Released by a goroutine which holds nothing.
+	sema.Release(1)
+
+	if holders := sema.Holders(); len(holders) != 0 {
+		t.Fatalf("expected no holders, got %v", holders)
+	}
+
+	sema.SetDebug(false)
+
+	if h := sema.Holders(); h != nil {
+		t.Fatalf("expected nil, got %v", h)
+	}
+}
+
+func TestWeightedSemaphore_SetLimitGrow(t *testing.T) {
+	sema := NewWeightedSemaphore(2)
+
+	sema.Acquire(context.Background(), 2)
+
+	done := make(chan error)
+
+	go func() {
+		done <- sema.Acquire(context.Background(), 2)
+	}()
+
+	waitWeightedWaiters(sema, 1)
+
+	// The waiter is granted without any release.
+	sema.SetLimit(4)
+
+	if err := <-done; err != nil {
+		t.Fatalf("failed to acquire: %v", err)
+	}
+
+	if l := sema.Limit(); l != 4 {
+		t.Fatalf("expected %d, got %d", 4, l)
+	}
+}
+
+func TestWeightedSemaphore_SetLimitShrink(t *testing.T) {
+	sema := NewWeightedSemaphore(4)
+
+	sema.Acquire(context.Background(), 4)
+	sema.SetLimit(2)
+
+	// Holders keep their resources, new requests wait until enough of them are released.
+	sema.Release(1)
+
+	if sema.TryAcquire(1) {
+		t.Fatal("expected not to acquire")
+	}
+
+	sema.Release(2)
+
+	if !sema.TryAcquire(1) {
+		t.Fatal("expected to acquire")
+	}
+
+	if sema.TryAcquire(1) {
+		t.Fatal("expected not to acquire")
+	}
+
+	if err := sema.Acquire(context.Background(), 3); err != ErrWeightTooLarge {
+		t.Fatalf("expected %v, got %v", ErrWeightTooLarge, err)
+	}
+}
+
+func TestWeightedSemaphore_SetLimitFailsWaiters(t *testing.T) {
+	sema := NewWeightedSemaphore(4)
+
+	sema.Acquire(context.Background(), 4)
+
+	large := make(chan error)
+
+	go func() {
+		large <- sema.Acquire(context.Background(), 3)
+	}()
+
+	waitWeightedWaiters(sema, 1)
+
+	small := make(chan error)
+
+	go func() {
+		small <- sema.Acquire(context.Background(), 1)
+	}()
+
+	waitWeightedWaiters(sema, 2)
+
+	sema.SetLimit(2)
+
+	if err := <-large; err != ErrWeightTooLarge {
+		t.Fatalf("expected %v, got %v", ErrWeightTooLarge, err)
+	}
+
+	sema.Release(4)
+
+	if err := <-small; err != nil {
+		t.Fatalf("failed to acquire: %v", err)
+	}
+}
+
+// waitWeightedWaiters blocks until n requests are waiting on the semaphore.
+func waitWeightedWaiters(s *WeightedSemaphore, n int) {
+	for {
+		s.mu.Lock()
+		l := s.waiters.Len()
+		s.mu.Unlock()
+
+		if l >= n {
+			return
+		}
+
+		time.Sleep(time.Millisecond)
+	}
+}